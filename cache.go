@@ -21,6 +21,8 @@ const (
 	driverRedis DriverType = "redis"
 	// driverMemory type memory
 	driverMemory DriverType = "memory"
+	// driverTiered type tiered, memory in front of redis
+	driverTiered DriverType = "tiered"
 )
 
 // Many type many
@@ -35,11 +37,20 @@ const (
 	NoExpirationTTL = time.Duration(-1)
 	// ItemNotExistedTTL item not existed ttl
 	ItemNotExistedTTL = time.Duration(-2)
+	// maxWatchRetries attempts of the WATCH transactions of Update and CompareAndSwap on redis
+	maxWatchRetries = 16
 )
 
 var (
 	ErrCacheMiss    = errors.New("cache not exists")
 	ErrCacheExisted = errors.New("cache already existed")
+	// ErrCacheConflict returned by Update and CompareAndSwap when the item keeps being modified by
+	// other clients, the operation is attempted 16 times on redis.
+	ErrCacheConflict = errors.New("cache item modified concurrently")
+	// ErrNotFound returned by the callbacks of Remember* when the item does not exist at the source,
+	// it is cached as a tombstone for the negative ttl of the driver, see WithNegativeTTL.
+	// errors.Is(ErrNotFound, ErrCacheMiss) is true.
+	ErrNotFound = fmt.Errorf("%w: item not found", ErrCacheMiss)
 )
 var (
 	defaultDriverName atomic.Value
@@ -82,25 +93,97 @@ type Driver[V any] interface {
 	// Decrement the value of an item in the cache.
 	Decrement(key string, n V) (V, error)
 	// Remember Get an item from the cache, or execute the given Closure and store the result.
+	// If the Closure returns ErrNotFound, a tombstone is cached and ErrNotFound is returned until it expires.
 	Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (V, error)
 	// RememberForever Get an item from the cache, or execute the given Closure and store the result forever.
 	RememberForever(key string, callback func() (V, error), force bool) (V, error)
 	// RememberMany Get many item from the cache, or execute the given Closure and store the result.
+	// Keys omitted from the result of the Closure are cached as tombstones and omitted from the result.
 	RememberMany(keys []string, ttl time.Duration, callback func(notHitKeys []string) (map[string]V, error), force bool) (map[string]V, error)
+	// RememberStale Get an item from the cache, or execute the given Closure and store the result.
+	// Within freshTTL the cached item is returned, within the following staleTTL the cached item is
+	// returned and refreshed once in the background, after that the Closure is executed synchronously.
+	RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error)
 	// TTL Get cache ttl
 	TTL(key string) (time.Duration, error)
-	// WithCtx with context
+	// Touch Set the ttl of an item without rewriting it, a ttl of 0 or NoExpirationTTL removes the expiration.
+	// ErrCacheMiss is returned if the item does not exist.
+	Touch(key string, ttl time.Duration) error
+	// TouchMany Set the ttl of multiple items, the missing keys are reported by an error wrapping ErrCacheMiss.
+	TouchMany(keys []string, ttl time.Duration) error
+	// ExpireAt Set the expiration time of an item, an item expiring in the past is removed.
+	// ErrCacheMiss is returned if the item does not exist.
+	ExpireAt(key string, at time.Time) error
+	// ExpireAtMany Set the expiration time of multiple items, the missing keys are reported by an error wrapping ErrCacheMiss.
+	ExpireAtMany(keys []string, at time.Time) error
+	// Persist Remove the expiration of an item, ErrCacheMiss is returned if the item does not exist.
+	Persist(key string) error
+	// PersistMany Remove the expiration of multiple items, the missing keys are reported by an error wrapping ErrCacheMiss.
+	PersistMany(keys []string) error
+	// GetAndTouch Retrieve an item from the cache by key and set its ttl, for sliding expiration.
+	GetAndTouch(key string, ttl time.Duration) (V, error)
+	// Pull Retrieve an item from the cache and remove it atomically.
+	Pull(key string) (V, error)
+	// GetSet Store an item in the cache and return the item it replaced atomically,
+	// ErrCacheMiss is returned if there was none, the item is stored anyway.
+	GetSet(key string, value V, t time.Duration) (V, error)
+	// CompareAndSwap Store an item in the cache if the serialized bytes of the cached item equal those
+	// of old, reports whether it is stored. The serializer must be deterministic.
+	CompareAndSwap(key string, old, value V, t time.Duration) (bool, error)
+	// Update Store the item returned by fn for the cached item, found is false if there is none.
+	// fn is called again if the item is modified concurrently, it may use the driver, e.g. to read other
+	// items. ErrCacheConflict is returned if the item keeps being modified.
+	Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (V, error)
+	// Scan Call fn with the keys of the items matching the glob pattern, without the prefix of the driver and
+	// in no particular order, batchSize keys are fetched at a time. fn returns ErrStopScan to stop the scan,
+	// other errors stop it and are returned. Redis may report a key more than once.
+	Scan(pattern string, batchSize int, fn func(key string) error) error
+	// ScanItems Call fn with the items matching the glob pattern and their ttl like Scan, tombstones and
+	// items which are not of the value type are skipped.
+	ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error
+	// Tags Get a view of the cache whose writes are recorded under the given tags,
+	// its Flush removes the tagged items only.
+	Tags(tags ...string) Driver[V]
+	// WithCtx Get a copy of the driver using ctx, the driver itself is not modified, so
+	// a driver can be shared by goroutines each deriving its own copy.
 	WithCtx(ctx context.Context) Driver[V]
-	// WithSerializer with cache serializer
+	// WithSerializer Get a copy of the driver using serializer, the driver itself is not modified.
 	WithSerializer(serializer Serializer) Driver[V]
 }
 
 type baseDriver struct {
+	name        string
 	driverType  DriverType
 	prefix      string
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
 	memCache    *gocache.Cache
 	serializer  Serializer
+	// negativeTTL ttl of the tombstones of items not found by Remember* callbacks, 0 disables negative caching
+	negativeTTL time.Duration
+	// localTTL upper bound of the memory copies kept by the tiered driver
+	localTTL time.Duration
+	// memStorage how the values are stored in memCache
+	memStorage MemoryStorage
+	// jitterPolicy random jitter added to the written ttls, nil disables jitter
+	jitterPolicy *JitterPolicy
+	// xfetch probabilistic early expiration of Remember, nil disables it
+	xfetch *XFetchPolicy
+	// chunkPolicy splits the batch operations of redis into chunks, nil sends every batch at once
+	chunkPolicy *ChunkPolicy
+	// namespace versioned namespace of the keys, shared by every Use of the driver, nil if the keys are not versioned
+	namespace *namespace
+	// bus publishes mutations to, and evicts memCache keys on invalidations from, other processes
+	bus *InvalidationBus
+	// group coalesces concurrent loads of Remember*, shared by every Use of the driver
+	group *flightGroup
+	// stats counters of the driver, shared by every Use of the driver
+	stats *driverStats
+	// middlewares intercepting every operation of the driver
+	middlewares []Middleware
+	// hooks invoked around every operation of the driver
+	hooks []Hook
+	// hashHookKeys report hashed keys to the hooks
+	hashHookKeys bool
 	// last error
 	ctx context.Context
 }
@@ -110,6 +193,8 @@ func newDriver(driverType DriverType, optionFns ...OptionFunc) (*baseDriver, err
 		driverType: driverType,
 		ctx:        context.Background(),
 		serializer: &JSONSerializer{},
+		group:      newFlightGroup(),
+		stats:      &driverStats{},
 	}
 	if len(optionFns) > 0 {
 		for _, optionFn := range optionFns {
@@ -118,13 +203,16 @@ func newDriver(driverType DriverType, optionFns ...OptionFunc) (*baseDriver, err
 			}
 		}
 	}
+	if baseDriver.bus != nil && baseDriver.memCache != nil {
+		baseDriver.bus.attach(baseDriver.memCache, baseDriver.prefix)
+	}
 	return &baseDriver, nil
 }
 
 // RegisterRedisDriver registers a Redis driver with the given driverName.
 // This function creates a new driver based on the provided redis client and registers it in registerDrivers.
-func RegisterRedisDriver(driverName string, redis *redis.Client, cacheKeyPrefix string) error {
-	d, err := newDriver(driverRedis, withRedisClient(redis), withPrefix(cacheKeyPrefix))
+func RegisterRedisDriver(driverName string, redis redis.UniversalClient, cacheKeyPrefix string, optionFns ...OptionFunc) error {
+	d, err := newDriver(driverRedis, append([]OptionFunc{withName(driverName), withRedisClient(redis), withPrefix(cacheKeyPrefix)}, optionFns...)...)
 	if err != nil {
 		return err
 	}
@@ -137,8 +225,8 @@ func RegisterRedisDriver(driverName string, redis *redis.Client, cacheKeyPrefix
 
 // RegisterGoCacheDriver registers a GoCache driver with the given driverName.
 // This function creates a new driver based on the provided go-cache client and registers it in registerDrivers.
-func RegisterGoCacheDriver(driverName string, memCache *gocache.Cache, cacheKeyPrefix string) error {
-	d, err := newDriver(driverMemory, withMemCache(memCache), withPrefix(cacheKeyPrefix))
+func RegisterGoCacheDriver(driverName string, memCache *gocache.Cache, cacheKeyPrefix string, optionFns ...OptionFunc) error {
+	d, err := newDriver(driverMemory, append([]OptionFunc{withName(driverName), withMemCache(memCache), withPrefix(cacheKeyPrefix)}, optionFns...)...)
 	if err != nil {
 		return err
 	}
@@ -149,6 +237,24 @@ func RegisterGoCacheDriver(driverName string, memCache *gocache.Cache, cacheKeyP
 	return nil
 }
 
+// RegisterTieredDriver registers a two-tier driver with the given driverName.
+// Reads are served from go-cache (L1) first and fall through to redis (L2), values read from redis are kept
+// in go-cache for at most localTTL. Writes go to redis and invalidate the go-cache copy.
+func RegisterTieredDriver(driverName string, memCache *gocache.Cache, redis redis.UniversalClient, cacheKeyPrefix string, localTTL time.Duration, optionFns ...OptionFunc) error {
+	if localTTL <= 0 {
+		return fmt.Errorf("tiered driver: %s local ttl must be positive", driverName)
+	}
+	d, err := newDriver(driverTiered, append([]OptionFunc{withName(driverName), withMemCache(memCache), withRedisClient(redis), withPrefix(cacheKeyPrefix), withLocalTTL(localTTL)}, optionFns...)...)
+	if err != nil {
+		return err
+	}
+	_, loaded := registerDrivers.LoadOrStore(driverName, d)
+	if loaded {
+		return fmt.Errorf("tiered driver: %s already registered", driverName)
+	}
+	return nil
+}
+
 // SetDefault set default driver
 func SetDefault(driverName string) {
 	defaultDriverName.Store(driverName)
@@ -173,32 +279,83 @@ func UseDefault[V any]() Driver[V] {
 func Use[V any](driverName string) (Driver[V], error) {
 	if value, ok := registerDrivers.Load(driverName); ok {
 		baseDriver := *value.(*baseDriver)
+		var driver Driver[V]
 		switch baseDriver.driverType {
 		case driverMemory:
-			return &GoCacheDriver[V]{
+			driver = &GoCacheDriver[V]{
 				baseDriver,
-			}, nil
+			}
 		case driverRedis:
-			return &RedisDriver[V]{
+			driver = &RedisDriver[V]{
 				baseDriver,
-			}, nil
+			}
+		case driverTiered:
+			driver = &TieredDriver[V]{
+				baseDriver,
+			}
 		default:
 			return nil, fmt.Errorf("unsupport driver type: %s", baseDriver.driverType)
 		}
+		if len(baseDriver.middlewares) > 0 {
+			driver = newMiddlewareDriver(driver)
+		}
+		if len(baseDriver.hooks) > 0 {
+			driver = newHookedDriver(driver)
+		}
+		return driver, nil
 	}
 	return nil, fmt.Errorf("cached driver: %s not registered", driverName)
 }
 
-// get cache key
-func (d *baseDriver) getCacheKey(key string) string {
+// getCacheKey the cache key of key, the error of the first read of the version of a versioned namespace
+// is returned
+func (d *baseDriver) getCacheKey(key string) (string, error) {
+	if d.namespace != nil {
+		version, err := d.namespaceVersion()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s:%s", d.namespacePrefix(version), key), nil
+	}
+	return d.prefixedKey(key), nil
+}
+
+// getCacheKeys the cache keys of keys, in the same generation of a versioned namespace
+func (d *baseDriver) getCacheKeys(keys []string) ([]string, error) {
+	prefix := d.prefix
+	if d.namespace != nil {
+		version, err := d.namespaceVersion()
+		if err != nil {
+			return nil, err
+		}
+		prefix = d.namespacePrefix(version)
+	}
+	return lo.Map(keys, func(key string, index int) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + ":" + key
+	}), nil
+}
+
+// prefixedKey the key with the prefix only, outside of the versioned namespace
+func (d *baseDriver) prefixedKey(key string) string {
 	if d.prefix == "" {
 		return key
 	}
 	return fmt.Sprintf("%s:%s", d.prefix, key)
 }
 
-func (d *baseDriver) getCacheKeys(keys []string) []string {
-	return lo.Map(keys, func(key string, index int) string {
-		return d.getCacheKey(key)
-	})
+// internalKeyPrefix the reserved segment of the bookkeeping keys, which no printable user key starts with
+const internalKeyPrefix = "\x00cacheit:"
+
+// internalKey the bookkeeping key with the prefix, outside of the versioned namespace
+func (d *baseDriver) internalKey(key string) string {
+	return d.prefixedKey(internalKeyPrefix + key)
+}
+
+// lockKey the key of the lock name, outside of the prefix so that a prefixed Flush of redis keeps the held locks,
+// the memory locks are kept out of the go-cache of the driver
+func (d *baseDriver) lockKey(name string) string {
+	return internalKeyPrefix + "lock:" + d.prefixedKey(name)
 }
@@ -1,7 +1,11 @@
 package cacheit
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -299,3 +303,483 @@ func testNumberCache[V any](t *testing.T, driver Driver[V], key string, value V)
 		}
 	})
 }
+
+func testRememberConcurrency(t *testing.T, driver Driver[string]) {
+	const goroutines = 50
+	t.Run("remember coalesces concurrent misses", func(t *testing.T) {
+		var calls int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		results := make([]string, goroutines)
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = driver.Remember("coalesce", time.Minute, func() (string, error) {
+					atomic.AddInt32(&calls, 1)
+					<-release
+					return "loaded", nil
+				}, false)
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		for _, result := range results {
+			assert.Equal(t, "loaded", result)
+		}
+		assert.NoError(t, driver.Flush())
+	})
+	t.Run("remember shares loader error", func(t *testing.T) {
+		var calls int32
+		loadErr := errors.New("load failed")
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		errs := make([]error, goroutines)
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = driver.RememberForever("coalesce_err", func() (string, error) {
+					atomic.AddInt32(&calls, 1)
+					<-release
+					return "", loadErr
+				}, false)
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		for _, err := range errs {
+			assert.ErrorIs(t, err, loadErr)
+		}
+		has, err := driver.Has("coalesce_err")
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+	t.Run("remember many loads overlapping keys once", func(t *testing.T) {
+		var mu sync.Mutex
+		loaded := make(map[string]int)
+		release := make(chan struct{})
+		keySets := [][]string{{"a", "b"}, {"b", "c"}, {"a", "b", "c"}}
+		var wg sync.WaitGroup
+		results := make([]map[string]string, goroutines)
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = driver.RememberMany(keySets[i%len(keySets)], time.Minute, func(notHitKeys []string) (map[string]string, error) {
+					mu.Lock()
+					for _, key := range notHitKeys {
+						loaded[key]++
+					}
+					mu.Unlock()
+					<-release
+					ret := make(map[string]string, len(notHitKeys))
+					for _, key := range notHitKeys {
+						ret[key] = "value_" + key
+					}
+					return ret, nil
+				}, false)
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, loaded)
+		for i, result := range results {
+			expected := make(map[string]string)
+			for _, key := range keySets[i%len(keySets)] {
+				expected[key] = "value_" + key
+			}
+			assert.Equal(t, expected, result)
+		}
+		assert.NoError(t, driver.Flush())
+	})
+}
+
+func testRememberStale(t *testing.T, driver Driver[string]) {
+	t.Run("remember stale", func(t *testing.T) {
+		var calls int32
+		release := make(chan struct{})
+		callback := func() (string, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return "v1", nil
+			}
+			<-release
+			return "v2", nil
+		}
+
+		got, err := driver.RememberStale("swr", 100*time.Millisecond, time.Minute, callback)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", got)
+		got, err = driver.RememberStale("swr", 100*time.Millisecond, time.Minute, callback)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", got)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		time.Sleep(150 * time.Millisecond)
+		for i := 0; i < 10; i++ {
+			got, err = driver.RememberStale("swr", 100*time.Millisecond, time.Minute, callback)
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", got)
+		}
+		close(release)
+		assert.Eventually(t, func() bool {
+			got, err := driver.Get("swr")
+			return err == nil && got == "v2"
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+		got, err = driver.RememberStale("swr", 100*time.Millisecond, time.Minute, callback)
+		assert.NoError(t, err)
+		assert.Equal(t, "v2", got)
+		assert.NoError(t, driver.Flush())
+	})
+	t.Run("remember stale loads synchronously after the stale window", func(t *testing.T) {
+		value := "v1"
+		callback := func() (string, error) {
+			return value, nil
+		}
+		got, err := driver.RememberStale("swr_expired", 10*time.Millisecond, 10*time.Millisecond, callback)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", got)
+
+		time.Sleep(50 * time.Millisecond)
+		value = "v2"
+		got, err = driver.RememberStale("swr_expired", 10*time.Millisecond, 10*time.Millisecond, callback)
+		assert.NoError(t, err)
+		assert.Equal(t, "v2", got)
+
+		many, err := driver.Many([]string{"swr_expired"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"swr_expired": "v2"}, many)
+		assert.NoError(t, driver.Flush())
+	})
+}
+
+func testNegativeCache(t *testing.T, driver Driver[string]) {
+	t.Run("remember caches not found", func(t *testing.T) {
+		var calls int32
+		callback := func() (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return "", ErrNotFound
+		}
+		for i := 0; i < 3; i++ {
+			_, err := driver.Remember("missing", time.Minute, callback, false)
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.ErrorIs(t, err, ErrCacheMiss)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		_, err := driver.Get("missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+		has, err := driver.Has("missing")
+		assert.NoError(t, err)
+		assert.False(t, has)
+		many, err := driver.Many([]string{"missing"})
+		assert.NoError(t, err)
+		assert.Empty(t, many)
+
+		// force reloads and the value replaces the tombstone
+		got, err := driver.Remember("missing", time.Minute, func() (string, error) {
+			return "found", nil
+		}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, "found", got)
+		got, err = driver.Get("missing")
+		assert.NoError(t, err)
+		assert.Equal(t, "found", got)
+		assert.NoError(t, driver.Flush())
+	})
+	t.Run("tombstones are stored with the negative ttl", func(t *testing.T) {
+		_, err := driver.Remember("expiring", time.Minute, func() (string, error) {
+			return "", ErrNotFound
+		}, false)
+		assert.ErrorIs(t, err, ErrNotFound)
+		ttl, err := driver.TTL("expiring")
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Duration(0))
+		assert.LessOrEqual(t, ttl, 2*time.Second)
+		assert.NoError(t, driver.Flush())
+	})
+	t.Run("remember many caches the keys not returned", func(t *testing.T) {
+		var loaded [][]string
+		callback := func(notHitKeys []string) (map[string]string, error) {
+			loaded = append(loaded, notHitKeys)
+			return map[string]string{"a": "1"}, nil
+		}
+		for i := 0; i < 2; i++ {
+			got, err := driver.RememberMany([]string{"a", "b"}, time.Minute, callback, false)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"a": "1"}, got)
+		}
+		assert.Len(t, loaded, 1)
+		assert.ElementsMatch(t, []string{"a", "b"}, loaded[0])
+		_, err := driver.Get("b")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, driver.Flush())
+	})
+	t.Run("other errors are not cached", func(t *testing.T) {
+		var calls int32
+		callback := func() (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return "", errors.New("backend down")
+		}
+		for i := 0; i < 2; i++ {
+			_, err := driver.Remember("failing", time.Minute, callback, false)
+			assert.EqualError(t, err, "backend down")
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		_, err := driver.Get("failing")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.NotErrorIs(t, err, ErrNotFound)
+	})
+}
+
+type mutableItem struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+	Child *mutableItem
+}
+
+func newMutableItem() mutableItem {
+	return mutableItem{
+		Name:  "item",
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]int{"a": 1},
+		Child: &mutableItem{Name: "child", Tags: []string{"c"}},
+	}
+}
+
+func mutate(item mutableItem) {
+	item.Tags[0] = "mutated"
+	item.Attrs["a"] = 42
+	item.Child.Name = "mutated"
+}
+
+// testIndependentValues the values returned by the driver are independent of the cached values
+func testIndependentValues(t *testing.T, driver Driver[mutableItem]) {
+	item := newMutableItem()
+	assert.NoError(t, driver.Set("key", item, time.Minute))
+	mutate(item)
+
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, newMutableItem(), got)
+	mutate(got)
+
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, newMutableItem(), got)
+
+	items, err := driver.Many([]string{"key"})
+	assert.NoError(t, err)
+	mutate(items["key"])
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, newMutableItem(), got)
+
+	for _, key := range []string{"key", "loaded"} {
+		got, err = driver.Remember(key, time.Minute, func() (mutableItem, error) {
+			return newMutableItem(), nil
+		}, false)
+		assert.NoError(t, err)
+		mutate(got)
+		got, err = driver.Get(key)
+		assert.NoError(t, err)
+		assert.Equal(t, newMutableItem(), got, key)
+	}
+}
+
+type ctxKey struct{}
+
+// testConcurrentWithCtx the drivers derived by WithCtx and WithSerializer are independent of each other and
+// of the shared driver, run with -race
+func testConcurrentWithCtx(t *testing.T, driver Driver[string]) {
+	shared := *driver.(baseProvider).base()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key_%d", i)
+			ctx := context.WithValue(context.Background(), ctxKey{}, key)
+			var serializer Serializer = &JSONSerializer{}
+			if i%2 == 1 {
+				serializer = &RawSerializer{}
+			}
+			derived := driver.WithCtx(ctx).WithSerializer(serializer)
+			assert.NoError(t, derived.Set(key, key, time.Minute))
+			got, err := derived.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, key, got)
+
+			base := derived.(baseProvider).base()
+			assert.Equal(t, key, base.ctx.Value(ctxKey{}))
+			assert.Same(t, serializer, base.serializer)
+		}(i)
+	}
+	wg.Wait()
+	base := driver.(baseProvider).base()
+	assert.Equal(t, shared.ctx, base.ctx)
+	assert.Same(t, shared.serializer, base.serializer)
+}
+
+func assertTTL(t *testing.T, driver Driver[string], key string, expected time.Duration) {
+	t.Helper()
+	ttl, err := driver.TTL(key)
+	assert.NoError(t, err)
+	if expected == NoExpirationTTL {
+		assert.Equal(t, NoExpirationTTL, ttl)
+		return
+	}
+	assert.LessOrEqual(t, ttl, expected)
+	assert.Greater(t, ttl, expected-time.Minute)
+}
+
+// cacheKey the cache key of key in driver
+func cacheKey(t *testing.T, driver baseProvider, key string) string {
+	t.Helper()
+	cacheKey, err := driver.base().getCacheKey(key)
+	assert.NoError(t, err)
+	return cacheKey
+}
+
+func testTouch(t *testing.T, driver Driver[string]) {
+	assert.ErrorIs(t, driver.Touch("missing", time.Minute), ErrCacheMiss)
+	assert.ErrorIs(t, driver.ExpireAt("missing", time.Now().Add(time.Minute)), ErrCacheMiss)
+	assert.ErrorIs(t, driver.Persist("missing"), ErrCacheMiss)
+	_, err := driver.GetAndTouch("missing", time.Minute)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	assert.NoError(t, driver.Touch("key", time.Hour))
+	assertTTL(t, driver, "key", time.Hour)
+	assert.NoError(t, driver.Persist("key"))
+	assertTTL(t, driver, "key", NoExpirationTTL)
+	assert.NoError(t, driver.Persist("key"), "persisting an item without expiration")
+
+	got, err := driver.GetAndTouch("key", 30*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", got)
+	assertTTL(t, driver, "key", 30*time.Minute)
+	assert.NoError(t, driver.Touch("key", 0))
+	assertTTL(t, driver, "key", NoExpirationTTL)
+
+	assert.NoError(t, driver.ExpireAt("key", time.Now().Add(2*time.Hour)))
+	assertTTL(t, driver, "key", 2*time.Hour)
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", got)
+	assert.NoError(t, driver.ExpireAt("key", time.Now().Add(-time.Minute)))
+	found, err := driver.Has("key")
+	assert.NoError(t, err)
+	assert.False(t, found, "items expiring in the past are removed")
+
+	assert.NoError(t, driver.Set("a", "a", time.Minute))
+	assert.NoError(t, driver.Set("b", "b", time.Minute))
+	err = driver.TouchMany([]string{"a", "b", "absent"}, time.Hour)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.EqualError(t, err, ErrCacheMiss.Error()+": absent")
+	assertTTL(t, driver, "a", time.Hour)
+	assertTTL(t, driver, "b", time.Hour)
+	assert.NoError(t, driver.PersistMany([]string{"a", "b"}))
+	assertTTL(t, driver, "a", NoExpirationTTL)
+	assert.NoError(t, driver.ExpireAtMany([]string{"a", "b"}, time.Now().Add(-time.Minute)))
+	items, err := driver.Many([]string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	assert.NoError(t, driver.TouchMany(nil, time.Hour))
+}
+
+func testTouchCapsTombstones(t *testing.T, driver Driver[string]) {
+	keys := []string{"touch", "persist", "expire_at", "get_and_touch"}
+	for _, key := range keys {
+		_, err := driver.Remember(key, time.Hour, func() (string, error) {
+			return "", ErrNotFound
+		}, false)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+
+	assert.NoError(t, driver.Touch("touch", time.Hour))
+	assert.NoError(t, driver.Persist("persist"))
+	assert.NoError(t, driver.ExpireAt("expire_at", time.Now().Add(time.Hour)))
+	_, err := driver.GetAndTouch("get_and_touch", time.Hour)
+	assert.ErrorIs(t, err, ErrNotFound)
+	for _, key := range keys {
+		assertTTL(t, driver, key, time.Minute)
+	}
+
+	assert.NoError(t, driver.Set("value", "value", time.Minute))
+	assert.NoError(t, driver.Persist("value"))
+	assertTTL(t, driver, "value", NoExpirationTTL)
+}
+
+func testAtomicOperations(t *testing.T, driver Driver[int]) {
+	_, err := driver.Pull("missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.NoError(t, driver.Set("pull", 1, time.Minute))
+	got, err := driver.Pull("pull")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got)
+	_, err = driver.Get("pull")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	_, err = driver.GetSet("swap", 1, time.Minute)
+	assert.ErrorIs(t, err, ErrCacheMiss, "the item is stored anyway")
+	got, err = driver.GetSet("swap", 2, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got)
+	got, err = driver.Get("swap")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got)
+
+	swapped, err := driver.CompareAndSwap("cas", 0, 1, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped, "missing items are not swapped")
+	assert.NoError(t, driver.Set("cas", 1, time.Minute))
+	swapped, err = driver.CompareAndSwap("cas", 2, 3, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = driver.CompareAndSwap("cas", 1, 3, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	got, err = driver.Get("cas")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got)
+
+	got, err = driver.Update("update", time.Minute, func(old int, found bool) (int, error) {
+		assert.False(t, found)
+		return old + 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got)
+	updateErr := errors.New("update failed")
+	_, err = driver.Update("update", time.Minute, func(old int, found bool) (int, error) {
+		return 0, updateErr
+	})
+	assert.ErrorIs(t, err, updateErr)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := driver.Update("update", time.Minute, func(old int, found bool) (int, error) {
+				return old + 1, nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	got, err = driver.Get("update")
+	assert.NoError(t, err)
+	assert.Equal(t, 11, got)
+}
@@ -0,0 +1,93 @@
+package cacheit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/samber/lo"
+)
+
+// ChunkPolicy how the batch operations of redis are split, see WithChunking
+type ChunkPolicy struct {
+	// Size maximum number of keys of an MGET, a DEL or a pipeline
+	Size int
+	// Parallelism maximum number of chunks sent at a time, chunks are sent one by one if not above 1
+	Parallelism int
+}
+
+// ChunkError the keys of the chunks of a batch operation which failed, or were not sent because the
+// context of the driver was done, the other keys succeeded. The writes of a failed chunk may be partially applied.
+type ChunkError struct {
+	// Keys keys of the failed chunks in the order of the operation
+	Keys []string
+	// Err error of the first failed chunk
+	Err error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("%d keys failed: %v", len(e.Keys), e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// chunkFailure the failed keys keys[start:end] of a chunked operation
+type chunkFailure struct {
+	start, end int
+	err        error
+}
+
+// chunked run fn on the chunks keys[start:end] of the chunk policy, the failed chunks are reported by a
+// *ChunkError. Without chunk policy fn runs once on every key and its error is returned as is.
+func (d *baseDriver) chunked(keys []string, fn func(start, end int) error) error {
+	if d.chunkPolicy == nil || len(keys) <= d.chunkPolicy.Size {
+		return fn(0, len(keys))
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []chunkFailure
+	)
+	fail := func(start, end int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, chunkFailure{start: start, end: end, err: err})
+	}
+	slots := make(chan struct{}, lo.Max([]int{d.chunkPolicy.Parallelism, 1}))
+	for start := 0; start < len(keys); start += d.chunkPolicy.Size {
+		end := lo.Min([]int{start + d.chunkPolicy.Size, len(keys)})
+		select {
+		case slots <- struct{}{}:
+		case <-d.ctx.Done():
+		}
+		// the chunks left are not sent once the context is done
+		if err := d.ctx.Err(); err != nil {
+			fail(start, len(keys), err)
+			break
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() {
+				<-slots
+			}()
+			if err := fn(start, end); err != nil {
+				fail(start, end, err)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].start < failures[j].start
+	})
+	chunkErr := &ChunkError{Err: failures[0].err}
+	for _, failure := range failures {
+		chunkErr.Keys = append(chunkErr.Keys, keys[failure.start:failure.end]...)
+	}
+	return chunkErr
+}
@@ -0,0 +1,211 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errChunkFailed = errors.New("chunk failed")
+
+// chunkHook records the number of keys of the MGET and DEL commands and of the pipelines sent to redis,
+// and fails those with the cache key failKey, only those named failName if it is set
+type chunkHook struct {
+	mu       sync.Mutex
+	sizes    map[string][]int
+	failKey  string
+	failName string
+	after    func()
+}
+
+func (h *chunkHook) record(name string, size int, args []any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sizes == nil {
+		h.sizes = make(map[string][]int)
+	}
+	h.sizes[name] = append(h.sizes[name], size)
+	if h.failName != "" && name != h.failName {
+		return nil
+	}
+	for _, arg := range args {
+		if arg == h.failKey {
+			return errChunkFailed
+		}
+	}
+	return nil
+}
+
+func (h *chunkHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if name := cmd.Name(); name == "mget" || name == "del" {
+		return ctx, h.record(name, len(cmd.Args())-1, cmd.Args())
+	}
+	return ctx, nil
+}
+
+func (h *chunkHook) AfterProcess(context.Context, redis.Cmder) error {
+	if h.after != nil {
+		h.after()
+	}
+	return nil
+}
+
+func (h *chunkHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	var args []any
+	for _, cmd := range cmds {
+		args = append(args, cmd.Args()...)
+	}
+	return ctx, h.record("pipeline", len(cmds), args)
+}
+
+func (h *chunkHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func setupChunkedDriver(t *testing.T, policy ChunkPolicy) (*RedisDriver[int], *chunkHook) {
+	driver := setupRedisDriverWithPrefix[int](t, "cache_prefix", WithChunking(policy))
+	hook := &chunkHook{}
+	driver.redisClient.AddHook(hook)
+	return driver, hook
+}
+
+func chunkItems(n int) ([]string, []Many[int]) {
+	keys := make([]string, n)
+	many := make([]Many[int], n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+		many[i] = Many[int]{Key: keys[i], Value: i, TTL: time.Minute}
+	}
+	return keys, many
+}
+
+func TestRedisChunking(t *testing.T) {
+	driver, hook := setupChunkedDriver(t, ChunkPolicy{Size: 3, Parallelism: 2})
+	keys, many := chunkItems(10)
+
+	assert.NoError(t, driver.SetMany(many))
+	assert.ElementsMatch(t, []int{3, 3, 3, 1}, hook.sizes["pipeline"])
+
+	items, err := driver.Many(append(keys, "missing"))
+	assert.NoError(t, err)
+	assert.Len(t, items, 10)
+	for i, key := range keys {
+		assert.Equal(t, i, items[key])
+	}
+	assert.ElementsMatch(t, []int{3, 3, 3, 2}, hook.sizes["mget"])
+
+	assert.NoError(t, driver.DelMany(keys))
+	assert.ElementsMatch(t, []int{3, 3, 3, 1}, hook.sizes["del"])
+	items, err = driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	assert.NoError(t, driver.SetMany(many[:3]))
+	assert.Equal(t, 3, hook.sizes["pipeline"][len(hook.sizes["pipeline"])-1], "small batches are sent at once")
+}
+
+func TestRedisChunkingPartialFailure(t *testing.T) {
+	driver, hook := setupChunkedDriver(t, ChunkPolicy{Size: 3, Parallelism: 4})
+	keys, many := chunkItems(10)
+	hook.failKey = cacheKey(t, driver, "key_4")
+
+	var chunkErr *ChunkError
+	err := driver.SetMany(many)
+	require.ErrorAs(t, err, &chunkErr)
+	assert.ErrorIs(t, err, errChunkFailed)
+	assert.Equal(t, keys[3:6], chunkErr.Keys)
+	assert.Equal(t, uint64(7), driver.stats.snapshot().Sets)
+
+	hook.failKey = cacheKey(t, driver, "key_7")
+	items, err := driver.Many(keys)
+	require.ErrorAs(t, err, &chunkErr)
+	assert.Equal(t, keys[6:9], chunkErr.Keys)
+	assert.Equal(t, map[string]int{"key_0": 0, "key_1": 1, "key_2": 2, "key_9": 9}, items,
+		"the items of the chunks which succeeded are returned")
+
+	err = driver.DelMany(keys)
+	require.ErrorAs(t, err, &chunkErr)
+	assert.Equal(t, keys[6:9], chunkErr.Keys)
+	hook.failKey = ""
+	items, err = driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"key_6": 6, "key_7": 7, "key_8": 8}, items)
+}
+
+func TestRedisChunkingRememberMany(t *testing.T) {
+	driver, hook := setupChunkedDriver(t, ChunkPolicy{Size: 3, Parallelism: 4})
+	keys, many := chunkItems(10)
+	assert.NoError(t, driver.SetMany(many))
+
+	hook.failKey, hook.failName = cacheKey(t, driver, "key_4"), "mget"
+	var loaded []string
+	items, err := driver.RememberMany(keys, time.Minute, func(notHitKeys []string) (map[string]int, error) {
+		loaded = notHitKeys
+		return map[string]int{"key_3": 30, "key_4": 40, "key_5": 50}, nil
+	}, false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, keys[3:6], loaded, "only the keys of the failed chunk are loaded")
+	assert.Len(t, items, 10)
+	assert.Equal(t, 9, items["key_9"])
+	assert.Equal(t, 40, items["key_4"])
+	assert.Equal(t, uint64(1), driver.stats.snapshot().Errors)
+}
+
+func TestRedisChunkingTombstones(t *testing.T) {
+	driver := setupRedisDriverWithPrefix[int](t, "cache_prefix", WithChunking(ChunkPolicy{Size: 3}), WithNegativeTTL(time.Minute))
+	hook := &chunkHook{}
+	driver.redisClient.AddHook(hook)
+	keys, _ := chunkItems(7)
+
+	items, err := driver.RememberMany(keys, time.Minute, func([]string) (map[string]int, error) {
+		return nil, nil
+	}, false)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Equal(t, []int{3, 3, 1}, hook.sizes["pipeline"], "the tombstones are written in chunks")
+}
+
+func TestRedisChunkingCancellation(t *testing.T) {
+	driver, hook := setupChunkedDriver(t, ChunkPolicy{Size: 3})
+	keys, many := chunkItems(10)
+	assert.NoError(t, driver.SetMany(many))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hook.after = cancel
+	items, err := driver.WithCtx(ctx).Many(keys)
+	var chunkErr *ChunkError
+	require.ErrorAs(t, err, &chunkErr)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, keys[3:], chunkErr.Keys, "the chunks left are not sent")
+	assert.Equal(t, map[string]int{"key_0": 0, "key_1": 1, "key_2": 2}, items)
+}
+
+func TestTieredChunking(t *testing.T) {
+	driver := setupTieredDriver[int](t, time.Minute, WithChunking(ChunkPolicy{Size: 2, Parallelism: 3}))
+	keys, many := chunkItems(7)
+	assert.NoError(t, driver.SetMany(many))
+	items, err := driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Len(t, items, 7)
+	assert.NoError(t, driver.DelMany(keys))
+	items, err = driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestWithChunkingRejectsInvalidPolicy(t *testing.T) {
+	memCache := gocache.New(time.Minute, time.Minute)
+	for _, policy := range []ChunkPolicy{{}, {Size: -1}, {Size: 1, Parallelism: -1}} {
+		err := RegisterGoCacheDriver(nextDriverName("mem_chunk"), memCache, "", WithChunking(policy))
+		assert.Error(t, err, fmt.Sprint(policy))
+	}
+	assert.EqualError(t, &ChunkError{Keys: []string{"a"}, Err: errChunkFailed}, "1 keys failed: chunk failed")
+}
@@ -0,0 +1,178 @@
+package cacheit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/samber/lo"
+)
+
+// clusterSlots number of hash slots of a redis cluster
+const clusterSlots = 16384
+
+// hashTag the part of key used for hashing, the content of the first non-empty {...} if any
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// crc16 CRC16-XMODEM used by redis cluster
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// keySlot redis cluster hash slot of key
+func keySlot(key string) int {
+	return int(crc16(hashTag(key))) % clusterSlots
+}
+
+// groupKeys split cache keys into groups which can be sent in a single multi-key command:
+// by hash slot on a cluster, by hash tag on a ring, and a single group otherwise.
+// The order of the groups and of the keys in each group follows keys.
+func (d *baseDriver) groupKeys(keys []string) [][]string {
+	var hash func(key string) string
+	switch d.redisClient.(type) {
+	case *redis.ClusterClient:
+		hash = func(key string) string {
+			return strconv.Itoa(keySlot(key))
+		}
+	case *redis.Ring:
+		hash = hashTag
+	default:
+		return [][]string{keys}
+	}
+	hashes := make(map[string]int)
+	var groups [][]string
+	for _, key := range keys {
+		h := hash(key)
+		i, ok := hashes[h]
+		if !ok {
+			i = len(groups)
+			hashes[h] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// forEachNode call fn with the client of every master of a cluster, every shard of a ring,
+// or the driver client itself.
+func (d *baseDriver) forEachNode(fn func(ctx context.Context, client redis.Cmdable) error) error {
+	switch client := d.redisClient.(type) {
+	case *redis.ClusterClient:
+		return client.ForEachMaster(d.ctx, func(ctx context.Context, client *redis.Client) error {
+			return fn(ctx, client)
+		})
+	case *redis.Ring:
+		return client.ForEachShard(d.ctx, func(ctx context.Context, client *redis.Client) error {
+			return fn(ctx, client)
+		})
+	default:
+		return fn(d.ctx, d.redisClient)
+	}
+}
+
+// delKeys delete cache keys with client, one DEL per key group
+func (d *baseDriver) delKeys(ctx context.Context, client redis.Cmdable, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	groups := d.groupKeys(keys)
+	if len(groups) == 1 {
+		return client.Del(ctx, keys...).Err()
+	}
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range groups {
+			pipe.Del(ctx, group...)
+		}
+		return nil
+	})
+	return err
+}
+
+// deleteMatching delete the keys matching pattern with SCAN
+func (d *baseDriver) deleteMatching(pattern string) error {
+	return d.deleteMatchingFunc(pattern, nil)
+}
+
+// deleteMatchingFunc delete the keys matching pattern with SCAN for which match, if not nil, returns true
+func (d *baseDriver) deleteMatchingFunc(pattern string, match func(key string) bool) error {
+	// keys are spread over every master of a cluster
+	return d.forEachNode(func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, nextCursor, err := client.Scan(ctx, cursor, pattern, 0).Result()
+			if err != nil {
+				return err
+			}
+			if match != nil {
+				keys = lo.Filter(keys, func(key string, _ int) bool {
+					return match(key)
+				})
+			}
+			if err = d.delKeys(ctx, client, keys); err != nil {
+				return err
+			}
+			if nextCursor == 0 {
+				return nil
+			}
+			cursor = nextCursor
+		}
+	})
+}
+
+// mget get cache keys with one MGET per key group, the values follow the order of keys.
+// When ttls is not nil the remaining ttl of every key is read into it in the same pipeline.
+func (d *baseDriver) mget(keys []string, ttls []time.Duration) ([]any, error) {
+	groups := d.groupKeys(keys)
+	if len(groups) == 1 && ttls == nil {
+		return d.redisClient.MGet(d.ctx, keys...).Result()
+	}
+	cmds := make([]*redis.SliceCmd, 0, len(groups))
+	pttls := make([]*redis.DurationCmd, 0, len(keys))
+	_, err := d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range groups {
+			cmds = append(cmds, pipe.MGet(d.ctx, group...))
+		}
+		if ttls != nil {
+			for _, key := range keys {
+				pttls = append(pttls, pipe.PTTL(d.ctx, key))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, cmd := range pttls {
+		ttls[i] = cmd.Val()
+	}
+	values := make(map[string]any, len(keys))
+	for i, cmd := range cmds {
+		for j, value := range cmd.Val() {
+			values[groups[i][j]] = value
+		}
+	}
+	return lo.Map(keys, func(key string, _ int) any {
+		return values[key]
+	}), nil
+}
@@ -0,0 +1,116 @@
+package cacheit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySlot(t *testing.T) {
+	assert.Equal(t, 12739, keySlot("123456789"))
+	assert.Equal(t, 12182, keySlot("foo"))
+	assert.Equal(t, keySlot("user1000"), keySlot("{user1000}.following"))
+	assert.Equal(t, keySlot("{user1000}.following"), keySlot("{user1000}.followers"))
+	assert.Equal(t, keySlot("{}.following"), keySlot("{}.following"))
+	assert.NotEqual(t, keySlot("foo"), keySlot("{}foo"))
+}
+
+func TestGroupKeys(t *testing.T) {
+	keys := []string{"{a}1", "{b}1", "{a}2", "{c}1", "{b}2"}
+
+	single := &baseDriver{redisClient: redis.NewClient(&redis.Options{})}
+	assert.Equal(t, [][]string{keys}, single.groupKeys(keys))
+
+	cluster := &baseDriver{redisClient: redis.NewClusterClient(&redis.ClusterOptions{})}
+	assert.Equal(t, [][]string{{"{a}1", "{a}2"}, {"{b}1", "{b}2"}, {"{c}1"}}, cluster.groupKeys(keys))
+
+	ring := &baseDriver{redisClient: redis.NewRing(&redis.RingOptions{})}
+	assert.Equal(t, [][]string{{"{a}1", "{a}2"}, {"{b}1", "{b}2"}, {"{c}1"}}, ring.groupKeys(keys))
+}
+
+func setupUniversalRedisDriver[V any](t *testing.T, client redis.UniversalClient, prefix string) *RedisDriver[V] {
+	t.Helper()
+	t.Cleanup(func() {
+		require.NoError(t, client.Close(), "close redis client")
+	})
+
+	driverName := nextDriverName("universal_test")
+	require.NoError(t, RegisterRedisDriver(driverName, client, prefix), "register redis driver")
+	driver, err := Use[V](driverName)
+	require.NoError(t, err, "use redis driver")
+	return driver.(*RedisDriver[V])
+}
+
+func TestRedisClusterDriver(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	driver := setupUniversalRedisDriver[string](t, redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: []string{mr.Addr()},
+	}), "cache_prefix")
+	testCache[string](t, driver, "test_string_key", "test_string_value")
+
+	assert.NoError(t, driver.Set("shared", "value", time.Minute))
+	mr.Set("unprefixed", "raw")
+	assert.NoError(t, driver.Flush())
+	assert.False(t, mr.Exists("cache_prefix:shared"))
+	assert.True(t, mr.Exists("unprefixed"))
+}
+
+func TestRedisRingDriver(t *testing.T) {
+	shards := make(map[string]string)
+	var servers []*miniredis.Miniredis
+	for _, name := range []string{"shard1", "shard2"} {
+		mr, err := miniredis.Run()
+		require.NoError(t, err)
+		t.Cleanup(mr.Close)
+		shards[name] = mr.Addr()
+		servers = append(servers, mr)
+	}
+
+	driver := setupUniversalRedisDriver[string](t, redis.NewRing(&redis.RingOptions{
+		Addrs: shards,
+	}), "cache_prefix")
+	testCache[string](t, driver, "test_string_key", "test_string_value")
+
+	keys := make([]string, 0, 20)
+	many := make([]Many[string], 0, 20)
+	for i := 0; i < 20; i++ {
+		key := "ring_" + string(rune('a'+i))
+		keys = append(keys, key)
+		many = append(many, Many[string]{Key: key, Value: key, TTL: time.Minute})
+	}
+	assert.NoError(t, driver.SetMany(many))
+	for _, mr := range servers {
+		assert.NotEmpty(t, mr.Keys(), "keys are spread over every shard")
+	}
+
+	got, err := driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Len(t, got, len(keys))
+
+	assert.NoError(t, driver.Flush())
+	for _, mr := range servers {
+		assert.Empty(t, mr.Keys())
+	}
+
+	assert.NoError(t, driver.SetMany(many))
+	assert.NoError(t, driver.DelMany(keys))
+	got, err = driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	assert.NoError(t, driver.redisClient.Set(context.Background(), "raw", "value", time.Minute).Err())
+	noPrefix := &RedisDriver[string]{driver.baseDriver}
+	noPrefix.prefix = ""
+	assert.NoError(t, noPrefix.Flush())
+	for _, mr := range servers {
+		assert.Empty(t, mr.Keys())
+	}
+}
@@ -0,0 +1,189 @@
+package cacheit
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// compressMagic starts the payloads written with a header by CompressSerializer, 0xC1 is never
+// used by MessagePack and never starts a JSON or gob payload.
+const compressMagic = 0xC1
+
+// codecNone codec id of the payloads stored uncompressed with a header
+const codecNone = 0x00
+
+// Codec compression codec of CompressSerializer
+type Codec interface {
+	// ID byte identifying the codec in the payload header, 0 is reserved
+	ID() byte
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCodec gzip codec, a Level of 0 uses gzip.DefaultCompression
+type GzipCodec struct {
+	Level int
+}
+
+func (c GzipCodec) ID() byte {
+	return 0x01
+}
+
+func (c GzipCodec) Compress(data []byte) ([]byte, error) {
+	return compress(data, func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level(c.Level, gzip.DefaultCompression))
+	})
+}
+
+func (c GzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return decompress(r)
+}
+
+// ZlibCodec zlib codec, a Level of 0 uses zlib.DefaultCompression
+type ZlibCodec struct {
+	Level int
+}
+
+func (c ZlibCodec) ID() byte {
+	return 0x02
+}
+
+func (c ZlibCodec) Compress(data []byte) ([]byte, error) {
+	return compress(data, func(w io.Writer) (io.WriteCloser, error) {
+		return zlib.NewWriterLevel(w, level(c.Level, zlib.DefaultCompression))
+	})
+}
+
+func (c ZlibCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return decompress(r)
+}
+
+// FlateCodec raw deflate codec, the smallest header, a Level of 0 uses flate.DefaultCompression
+type FlateCodec struct {
+	Level int
+}
+
+func (c FlateCodec) ID() byte {
+	return 0x03
+}
+
+func (c FlateCodec) Compress(data []byte) ([]byte, error) {
+	return compress(data, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level(c.Level, flate.DefaultCompression))
+	})
+}
+
+func (c FlateCodec) Decompress(data []byte) ([]byte, error) {
+	return decompress(flate.NewReader(bytes.NewReader(data)))
+}
+
+func level(level, defaultLevel int) int {
+	if level == 0 {
+		return defaultLevel
+	}
+	return level
+}
+
+func compress(data []byte, newWriter func(w io.Writer) (io.WriteCloser, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(r io.ReadCloser) ([]byte, error) {
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// CompressSerializer compresses the output of an inner serializer above a size threshold.
+// Compressed payloads start with a header of 0xC1 and the codec id, payloads below the threshold are
+// stored as the inner serializer wrote them, so payloads written without the wrapper are still read.
+type CompressSerializer struct {
+	inner     Serializer
+	codec     Codec
+	threshold int
+	codecs    map[byte]Codec
+}
+
+// NewCompressSerializer creates a serializer compressing the payloads of inner of at least threshold bytes
+// with codec. Payloads compressed by the built-in codecs, codec and decoders are decompressed, so the codec
+// can be changed without flushing the cache.
+func NewCompressSerializer(inner Serializer, codec Codec, threshold int, decoders ...Codec) (*CompressSerializer, error) {
+	codecs := make(map[byte]Codec)
+	for _, c := range append([]Codec{GzipCodec{}, ZlibCodec{}, FlateCodec{}, codec}, decoders...) {
+		if c == nil {
+			return nil, errors.New("compression codec is nil")
+		}
+		if c.ID() == codecNone {
+			return nil, fmt.Errorf("compression codec %T: id 0x00 is reserved", c)
+		}
+		codecs[c.ID()] = c
+	}
+	return &CompressSerializer{
+		inner:     inner,
+		codec:     codec,
+		threshold: threshold,
+		codecs:    codecs,
+	}, nil
+}
+
+// Serialize Serialize data
+func (s *CompressSerializer) Serialize(v any) ([]byte, error) {
+	data, err := s.inner.Serialize(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < s.threshold {
+		// payloads which could be mistaken for a header are stored with a header too
+		if len(data) > 0 && data[0] == compressMagic {
+			return append([]byte{compressMagic, codecNone}, data...), nil
+		}
+		return data, nil
+	}
+	compressed, err := s.codec.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{compressMagic, s.codec.ID()}, compressed...), nil
+}
+
+// UnSerialize UnSerialize data
+func (s *CompressSerializer) UnSerialize(data []byte, v any) error {
+	if len(data) < 2 || data[0] != compressMagic {
+		return s.inner.UnSerialize(data, v)
+	}
+	payload := data[2:]
+	if data[1] != codecNone {
+		codec, ok := s.codecs[data[1]]
+		if !ok {
+			return fmt.Errorf("unknown compression codec: %#x", data[1])
+		}
+		var err error
+		if payload, err = codec.Decompress(payload); err != nil {
+			return fmt.Errorf("decompress: %w", err)
+		}
+	}
+	return s.inner.UnSerialize(payload, v)
+}
@@ -0,0 +1,147 @@
+package cacheit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseCodec a custom codec for tests
+type reverseCodec struct{}
+
+func (reverseCodec) ID() byte {
+	return 0x10
+}
+
+func (reverseCodec) Compress(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[len(data)-1-i] = b
+	}
+	return out, nil
+}
+
+func (c reverseCodec) Decompress(data []byte) ([]byte, error) {
+	return c.Compress(data)
+}
+
+// noneCodec a codec using the reserved id
+type noneCodec struct {
+	reverseCodec
+}
+
+func (noneCodec) ID() byte {
+	return codecNone
+}
+
+// bytesSerializer writes []byte values as is
+type bytesSerializer struct{}
+
+func (bytesSerializer) Serialize(v any) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (bytesSerializer) UnSerialize(data []byte, v any) error {
+	*v.(*[]byte) = append([]byte{}, data...)
+	return nil
+}
+
+// mustCompress a CompressSerializer of valid codecs
+func mustCompress(t *testing.T, inner Serializer, codec Codec, threshold int, decoders ...Codec) *CompressSerializer {
+	t.Helper()
+	serializer, err := NewCompressSerializer(inner, codec, threshold, decoders...)
+	require.NoError(t, err)
+	return serializer
+}
+
+func TestCompressSerializer(t *testing.T) {
+	large := testStruct{Message: strings.Repeat("compressible ", 100)}
+	for _, codec := range []Codec{GzipCodec{}, ZlibCodec{Level: 9}, FlateCodec{Level: 1}, reverseCodec{}} {
+		serializer := mustCompress(t, &JSONSerializer{}, codec, 256)
+
+		data, err := serializer.Serialize(large)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{compressMagic, codec.ID()}, data[:2])
+		var got testStruct
+		assert.NoError(t, serializer.UnSerialize(data, &got))
+		assert.Equal(t, large, got)
+
+		// small payloads are written as is
+		data, err = serializer.Serialize(testStructData)
+		assert.NoError(t, err)
+		legacy, err := (&JSONSerializer{}).Serialize(testStructData)
+		assert.NoError(t, err)
+		assert.Equal(t, legacy, data)
+		got = testStruct{}
+		assert.NoError(t, serializer.UnSerialize(legacy, &got))
+		assert.Equal(t, testStructData, got)
+	}
+
+	t.Run("changing the codec keeps compressed payloads readable", func(t *testing.T) {
+		data, err := mustCompress(t, &JSONSerializer{}, GzipCodec{}, 0).Serialize(large)
+		assert.NoError(t, err)
+		var got testStruct
+		assert.NoError(t, mustCompress(t, &JSONSerializer{}, FlateCodec{}, 0).UnSerialize(data, &got))
+		assert.Equal(t, large, got)
+
+		data, err = mustCompress(t, &JSONSerializer{}, reverseCodec{}, 0).Serialize(large)
+		assert.NoError(t, err)
+		assert.EqualError(t, mustCompress(t, &JSONSerializer{}, GzipCodec{}, 0).UnSerialize(data, &got), "unknown compression codec: 0x10")
+		assert.NoError(t, mustCompress(t, &JSONSerializer{}, GzipCodec{}, 0, reverseCodec{}).UnSerialize(data, &got))
+	})
+	t.Run("payloads starting like a header", func(t *testing.T) {
+		serializer := mustCompress(t, bytesSerializer{}, GzipCodec{}, 1024)
+		raw := []byte{compressMagic, GzipCodec{}.ID(), 'x'}
+		data, err := serializer.Serialize(raw)
+		assert.NoError(t, err)
+		var got []byte
+		assert.NoError(t, serializer.UnSerialize(data, &got))
+		assert.Equal(t, raw, got)
+	})
+	t.Run("invalid codecs", func(t *testing.T) {
+		_, err := NewCompressSerializer(&JSONSerializer{}, nil, 0)
+		assert.EqualError(t, err, "compression codec is nil")
+		_, err = NewCompressSerializer(&JSONSerializer{}, GzipCodec{}, 0, nil)
+		assert.EqualError(t, err, "compression codec is nil")
+		_, err = NewCompressSerializer(&JSONSerializer{}, noneCodec{}, 0)
+		assert.EqualError(t, err, "compression codec cacheit.noneCodec: id 0x00 is reserved")
+		_, err = NewCompressSerializer(&JSONSerializer{}, GzipCodec{}, 0, noneCodec{})
+		assert.Error(t, err)
+	})
+	t.Run("corrupted payload", func(t *testing.T) {
+		serializer := mustCompress(t, &JSONSerializer{}, GzipCodec{}, 0)
+		var got testStruct
+		assert.ErrorContains(t, serializer.UnSerialize([]byte{compressMagic, GzipCodec{}.ID(), 'x'}, &got), "decompress")
+	})
+}
+
+func TestRedisCompressSerializer(t *testing.T) {
+	driver := setupRedisDriver[testStruct](t)
+	legacy := driver.WithSerializer(&JSONSerializer{})
+	assert.NoError(t, legacy.Set("legacy", testStructData, time.Minute))
+
+	compressed := driver.WithSerializer(mustCompress(t, &JSONSerializer{}, GzipCodec{}, 128))
+	large := testStruct{Message: strings.Repeat("compressible ", 100), IntSlice: []int{1, 2, 3}}
+	assert.NoError(t, compressed.Set("large", large, time.Minute))
+	raw, err := driver.redisClient.Get(driver.ctx, cacheKey(t, driver, "large")).Bytes()
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, []byte{compressMagic, GzipCodec{}.ID()}))
+	assert.Less(t, len(raw), len(large.Message))
+
+	many, err := compressed.Many([]string{"legacy", "large"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]testStruct{"legacy": testStructData, "large": large}, many)
+
+	got, err := compressed.RememberStale("stale", time.Minute, time.Minute, func() (testStruct, error) {
+		return large, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, large, got)
+	got, err = compressed.Get("stale")
+	assert.NoError(t, err)
+	assert.Equal(t, large, got)
+}
@@ -0,0 +1,122 @@
+package cacheit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptMagic starts the payloads written by EncryptSerializer
+const encryptMagic = 0xC2
+
+var (
+	// errNotEncrypted the payload was not written by EncryptSerializer
+	errNotEncrypted = errors.New("payload is not encrypted")
+	// errUnknownKey the payload was encrypted with a key unknown to the serializer
+	errUnknownKey = errors.New("unknown encryption key")
+)
+
+// DecryptError returned by EncryptSerializer, and so by Get and Many, when a payload can not be
+// decrypted: not encrypted, encrypted with an unknown key, or failing authentication.
+type DecryptError struct {
+	// KeyID id of the key in the payload header, empty if the header is invalid
+	KeyID string
+	Err   error
+}
+
+func (e *DecryptError) Error() string {
+	if e.KeyID == "" {
+		return fmt.Sprintf("decrypt cache payload: %v", e.Err)
+	}
+	return fmt.Sprintf("decrypt cache payload with key %q: %v", e.KeyID, e.Err)
+}
+
+func (e *DecryptError) Unwrap() error {
+	return e.Err
+}
+
+// EncryptionKey AES key of EncryptSerializer, 16, 24 or 32 bytes for AES-128, AES-192 or AES-256
+type EncryptionKey struct {
+	// ID id stored in the payload header, at most 255 bytes
+	ID  string
+	Key []byte
+}
+
+// EncryptSerializer encrypts the output of an inner serializer with AES-GCM.
+// Payloads are a header of 0xC2, the key id length and the key id, then the nonce and the sealed
+// payload, the header is authenticated.
+type EncryptSerializer struct {
+	inner   Serializer
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewEncryptSerializer creates a serializer encrypting the payloads of inner with current, payloads
+// encrypted with current or any of previous are decrypted, so keys can be rotated without flushing the cache.
+func NewEncryptSerializer(inner Serializer, current EncryptionKey, previous ...EncryptionKey) (*EncryptSerializer, error) {
+	s := &EncryptSerializer{
+		inner:   inner,
+		current: current.ID,
+		aeads:   make(map[string]cipher.AEAD),
+	}
+	for _, key := range append([]EncryptionKey{current}, previous...) {
+		if key.ID == "" || len(key.ID) > 255 {
+			return nil, fmt.Errorf("encryption key id must be 1 to 255 bytes: %q", key.ID)
+		}
+		if _, ok := s.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate encryption key id: %q", key.ID)
+		}
+		block, err := aes.NewCipher(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		s.aeads[key.ID] = aead
+	}
+	return s, nil
+}
+
+// Serialize Serialize data
+func (s *EncryptSerializer) Serialize(v any) ([]byte, error) {
+	data, err := s.inner.Serialize(v)
+	if err != nil {
+		return nil, err
+	}
+	aead := s.aeads[s.current]
+	header := append([]byte{encryptMagic, byte(len(s.current))}, s.current...)
+	out := make([]byte, len(header)+aead.NonceSize(), len(header)+aead.NonceSize()+len(data)+aead.Overhead())
+	copy(out, header)
+	nonce := out[len(header):]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, data, header), nil
+}
+
+// UnSerialize UnSerialize data
+func (s *EncryptSerializer) UnSerialize(data []byte, v any) error {
+	if len(data) < 2 || data[0] != encryptMagic || len(data) < 2+int(data[1]) {
+		return &DecryptError{Err: errNotEncrypted}
+	}
+	headerSize := 2 + int(data[1])
+	keyID := string(data[2:headerSize])
+	aead, ok := s.aeads[keyID]
+	if !ok {
+		return &DecryptError{KeyID: keyID, Err: errUnknownKey}
+	}
+	if len(data) < headerSize+aead.NonceSize() {
+		return &DecryptError{KeyID: keyID, Err: errNotEncrypted}
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+	if err != nil {
+		return &DecryptError{KeyID: keyID, Err: err}
+	}
+	return s.inner.UnSerialize(plain, v)
+}
@@ -0,0 +1,103 @@
+package cacheit
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testKeyV1 = EncryptionKey{ID: "v1", Key: bytes.Repeat([]byte{1}, 32)}
+	testKeyV2 = EncryptionKey{ID: "v2", Key: bytes.Repeat([]byte{2}, 16)}
+)
+
+func TestEncryptSerializer(t *testing.T) {
+	v1, err := NewEncryptSerializer(&JSONSerializer{}, testKeyV1)
+	require.NoError(t, err)
+
+	data, err := v1.Serialize(testStructData)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{encryptMagic, 2, 'v', '1'}, data[:4])
+	assert.NotContains(t, string(data), testStructData.Message)
+	var got testStruct
+	assert.NoError(t, v1.UnSerialize(data, &got))
+	assert.Equal(t, testStructData, got)
+
+	again, err := v1.Serialize(testStructData)
+	assert.NoError(t, err)
+	assert.NotEqual(t, data, again, "nonces must differ")
+
+	t.Run("rotation", func(t *testing.T) {
+		v2, err := NewEncryptSerializer(&JSONSerializer{}, testKeyV2, testKeyV1)
+		require.NoError(t, err)
+		var got testStruct
+		assert.NoError(t, v2.UnSerialize(data, &got))
+		assert.Equal(t, testStructData, got)
+
+		rotated, err := v2.Serialize(testStructData)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{encryptMagic, 2, 'v', '2'}, rotated[:4])
+
+		var decryptErr *DecryptError
+		assert.ErrorAs(t, v1.UnSerialize(rotated, &got), &decryptErr)
+		assert.Equal(t, "v2", decryptErr.KeyID)
+		assert.ErrorIs(t, decryptErr, errUnknownKey)
+	})
+	t.Run("tampered and plain payloads", func(t *testing.T) {
+		tampered := append([]byte{}, data...)
+		tampered[len(tampered)-1] ^= 0xFF
+		var decryptErr *DecryptError
+		assert.ErrorAs(t, v1.UnSerialize(tampered, &got), &decryptErr)
+		assert.Equal(t, "v1", decryptErr.KeyID)
+
+		// the key id is authenticated
+		renamed := append([]byte{encryptMagic, 2, 'v', '2'}, data[4:]...)
+		v2, err := NewEncryptSerializer(&JSONSerializer{}, EncryptionKey{ID: "v2", Key: testKeyV1.Key})
+		require.NoError(t, err)
+		assert.ErrorAs(t, v2.UnSerialize(renamed, &got), &decryptErr)
+
+		for _, payload := range [][]byte{[]byte(`{"Value":1}`), {encryptMagic}, {encryptMagic, 5, 'v'}, {encryptMagic, 2, 'v', '1', 0}} {
+			assert.ErrorAs(t, v1.UnSerialize(payload, &got), &decryptErr)
+		}
+	})
+	t.Run("invalid keys", func(t *testing.T) {
+		_, err := NewEncryptSerializer(&JSONSerializer{}, EncryptionKey{ID: "short", Key: []byte("short")})
+		assert.Error(t, err)
+		_, err = NewEncryptSerializer(&JSONSerializer{}, EncryptionKey{Key: testKeyV1.Key})
+		assert.Error(t, err)
+		_, err = NewEncryptSerializer(&JSONSerializer{}, testKeyV1, testKeyV1)
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisEncryptSerializer(t *testing.T) {
+	v1, err := NewEncryptSerializer(mustCompress(t, &JSONSerializer{}, GzipCodec{}, 64), testKeyV1)
+	require.NoError(t, err)
+	other, err := NewEncryptSerializer(&JSONSerializer{}, testKeyV2)
+	require.NoError(t, err)
+
+	redisDriver := setupRedisDriver[testStruct](t)
+	driver := redisDriver.WithSerializer(v1)
+	assert.NoError(t, driver.Set("a", testStructData, time.Minute))
+	assert.NoError(t, driver.Set("b", testStructData, time.Minute))
+	raw, err := redisDriver.redisClient.Get(redisDriver.ctx, cacheKey(t, redisDriver, "a")).Bytes()
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), testStructData.Message)
+
+	many, err := driver.Many([]string{"a", "b", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]testStruct{"a": testStructData, "b": testStructData}, many)
+
+	driver = driver.WithSerializer(other)
+	var decryptErr *DecryptError
+	_, err = driver.Get("a")
+	assert.True(t, errors.As(err, &decryptErr))
+	assert.Equal(t, "v1", decryptErr.KeyID)
+	_, err = driver.Many([]string{"a", "b"})
+	assert.ErrorAs(t, err, &decryptErr)
+	assert.Equal(t, uint64(2), redisDriver.stats.snapshot().Errors)
+}
@@ -0,0 +1,170 @@
+package cacheit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	// entryMagic starts the redis payloads of the values stored with their metadata
+	entryMagic = []byte{0x00, 'c', 'i', 0x01}
+	// tombstoneMagic the redis payload of a negative cached item
+	tombstoneMagic = []byte{0x00, 'c', 'i', 0x02}
+	// timedEntryMagic starts the redis payloads of the values stored with their loading time and ttl
+	timedEntryMagic = []byte{0x00, 'c', 'i', 0x03}
+)
+
+const (
+	// entryHeaderSize size of the magic and the metadata preceding the serialized value
+	entryHeaderSize = 4 + 8
+	// timedEntryHeaderSize size of the magic and the metadata preceding the serialized value of the timed entries
+	timedEntryHeaderSize = entryHeaderSize + 8 + 8
+)
+
+// entryMeta metadata stored alongside a value
+type entryMeta struct {
+	// writtenAt the time the value was written, zero if it was written without metadata
+	writtenAt time.Time
+	// delta the time the loader took to compute the value, zero if unknown
+	delta time.Duration
+	// ttl the ttl the value was written with, jitter included, zero if unknown
+	ttl time.Duration
+}
+
+// written the metadata of a value written with ttl, the jittered ttl the store actually wrote
+func (m entryMeta) written(ttl time.Duration) entryMeta {
+	if m.ttl != 0 {
+		m.ttl = ttl
+	}
+	return m
+}
+
+// encodeEntry prepend the metadata to a serialized value
+func encodeEntry(meta entryMeta, payload []byte) []byte {
+	if meta.delta == 0 && meta.ttl == 0 {
+		data := make([]byte, entryHeaderSize, entryHeaderSize+len(payload))
+		copy(data, entryMagic)
+		binary.BigEndian.PutUint64(data[len(entryMagic):], uint64(meta.writtenAt.UnixNano()))
+		return append(data, payload...)
+	}
+	data := make([]byte, timedEntryHeaderSize, timedEntryHeaderSize+len(payload))
+	copy(data, timedEntryMagic)
+	binary.BigEndian.PutUint64(data[len(timedEntryMagic):], uint64(meta.writtenAt.UnixNano()))
+	binary.BigEndian.PutUint64(data[entryHeaderSize:], uint64(meta.delta))
+	binary.BigEndian.PutUint64(data[entryHeaderSize+8:], uint64(meta.ttl))
+	return append(data, payload...)
+}
+
+// decodeEntry split the metadata and the serialized value, data written without metadata is returned as is
+func decodeEntry(data []byte) (entryMeta, []byte) {
+	if len(data) >= timedEntryHeaderSize && bytes.HasPrefix(data, timedEntryMagic) {
+		return entryMeta{
+			writtenAt: time.Unix(0, int64(binary.BigEndian.Uint64(data[len(timedEntryMagic):]))),
+			delta:     time.Duration(binary.BigEndian.Uint64(data[entryHeaderSize:])),
+			ttl:       time.Duration(binary.BigEndian.Uint64(data[entryHeaderSize+8:])),
+		}, data[timedEntryHeaderSize:]
+	}
+	if len(data) < entryHeaderSize || !bytes.HasPrefix(data, entryMagic) {
+		return entryMeta{}, data
+	}
+	writtenAt := int64(binary.BigEndian.Uint64(data[len(entryMagic):]))
+	return entryMeta{writtenAt: time.Unix(0, writtenAt)}, data[entryHeaderSize:]
+}
+
+// isTombstone report whether a redis payload is a negative cached item
+func isTombstone(data []byte) bool {
+	return bytes.Equal(data, tombstoneMagic)
+}
+
+// tombstone the go-cache value of a negative cached item
+type tombstone struct{}
+
+// memEntry a value stored with its metadata in go-cache
+type memEntry[V any] struct {
+	value V
+	meta  entryMeta
+}
+
+// entryStore implemented by drivers able to store values with their metadata
+type entryStore[V any] interface {
+	baseProvider
+	// getEntry Retrieve an item and its metadata from the cache by key.
+	getEntry(key string) (V, entryMeta, error)
+	// setEntry Store an item and its metadata in the cache.
+	setEntry(key string, value V, meta entryMeta, ttl time.Duration) error
+	// setTombstones Store negative cached items for the keys not found by a loader.
+	setTombstones(keys []string) error
+}
+
+// rememberStale implements RememberStale on top of an entryStore, detached returns the store used by
+// background refreshes.
+func rememberStale[V any](store entryStore[V], detached func() entryStore[V], key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	d := store.base()
+	refresh := func(store entryStore[V]) func() (V, error) {
+		return func() (V, error) {
+			result, err := load(d.stats, callback)
+			if errors.Is(err, ErrNotFound) {
+				if err := store.setTombstones([]string{key}); err != nil {
+					return result, err
+				}
+			}
+			if err != nil {
+				return result, err
+			}
+			return result, store.setEntry(key, result, entryMeta{writtenAt: time.Now()}, freshTTL+staleTTL)
+		}
+	}
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		var result V
+		return result, d.stats.fail(err)
+	}
+	result, meta, err := store.getEntry(key)
+	d.stats.lookup(err)
+	if errors.Is(err, ErrNotFound) {
+		return result, err
+	}
+	if err == nil {
+		age := time.Since(meta.writtenAt)
+		if meta.writtenAt.IsZero() || age < freshTTL {
+			return result, nil
+		}
+		if age < freshTTL+staleTTL {
+			d.group.DoAsync(cacheKey, func() (any, error) {
+				return refresh(detached())()
+			})
+			return result, nil
+		}
+	}
+	return doFlight(d.ctx, d.group, cacheKey, refresh(store))
+}
+
+// detachedContext keeps the values of its parent but not its deadline and cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+// detachContext derive a context which is never canceled from parent, for background work
+// outliving the caller.
+func detachContext(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (c detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
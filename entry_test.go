@@ -0,0 +1,48 @@
+package cacheit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeEntry(t *testing.T) {
+	writtenAt := time.Unix(0, time.Now().UnixNano())
+	data := encodeEntry(entryMeta{writtenAt: writtenAt}, []byte(`"value"`))
+
+	meta, payload := decodeEntry(data)
+	assert.True(t, writtenAt.Equal(meta.writtenAt))
+	assert.Equal(t, []byte(`"value"`), payload)
+
+	meta, payload = decodeEntry([]byte(`"legacy"`))
+	assert.True(t, meta.writtenAt.IsZero())
+	assert.Equal(t, []byte(`"legacy"`), payload)
+}
+
+func TestEncodeDecodeTimedEntry(t *testing.T) {
+	written := entryMeta{writtenAt: time.Unix(0, time.Now().UnixNano()), delta: 15 * time.Millisecond, ttl: time.Minute}
+	data := encodeEntry(written, []byte(`"value"`))
+	assert.Len(t, data, timedEntryHeaderSize+len(`"value"`))
+
+	meta, payload := decodeEntry(data)
+	assert.True(t, written.writtenAt.Equal(meta.writtenAt))
+	assert.Equal(t, written.delta, meta.delta)
+	assert.Equal(t, written.ttl, meta.ttl)
+	assert.Equal(t, []byte(`"value"`), payload)
+}
+
+type contextKey struct{}
+
+func TestDetachContext(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), contextKey{}, "value"), time.Minute)
+	cancel()
+
+	ctx := detachContext(parent)
+	assert.Equal(t, "value", ctx.Value(contextKey{}))
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+}
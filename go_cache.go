@@ -1,9 +1,11 @@
 package cacheit
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	gocache "github.com/patrickmn/go-cache"
@@ -11,43 +13,159 @@ import (
 	"github.com/spf13/cast"
 )
 
+// MemoryStorage how the go-cache driver, and the memory tier of the tiered driver, store the values
+type MemoryStorage int
+
+const (
+	// StoreValues store the values themselves, the values returned by Get, Many and Remember* share
+	// their pointers, slices and maps with the cache, so they must not be mutated.
+	StoreValues MemoryStorage = iota
+	// StoreSerialized store the values serialized with the serializer of the driver, every read
+	// unserializes an independent value, like the redis driver.
+	StoreSerialized
+	// StoreCopies store deep copies of the values and return deep copies of them, the exported
+	// fields of structs are copied, unexported fields are shallow copied.
+	StoreCopies
+)
+
+// memKeyLocks serializes the writes of the go-cache items by cache key, so that the plain writes do not
+// interleave with the read-modify-write operations
+var memKeyLocks keyMutex
+
+// memStateCleanupInterval how often the expired locks and rate limiters state are removed from memory
+const memStateCleanupInterval = time.Minute
+
+// memStates the go-cache keeping the locks and rate limiters state of each go-cache of the drivers, out of
+// the reach of Flush and of the invalidation bus
+var memStates sync.Map
+
+// memState the go-cache keeping the locks and rate limiters state of the go-cache of the driver
+func (d *baseDriver) memState() *gocache.Cache {
+	if state, ok := memStates.Load(d.memCache); ok {
+		return state.(*gocache.Cache)
+	}
+	state, _ := memStates.LoadOrStore(d.memCache, gocache.New(gocache.NoExpiration, memStateCleanupInterval))
+	return state.(*gocache.Cache)
+}
+
 // GoCacheDriver go-cache driver implemented
 type GoCacheDriver[V any] struct {
 	baseDriver
 }
 
+// serializedEntry a value stored serialized in go-cache, with its metadata
+type serializedEntry struct {
+	data []byte
+	meta entryMeta
+}
+
+// encode the value and its metadata as stored in go-cache by the memory storage of the driver
+func (d *GoCacheDriver[V]) encode(value V, meta entryMeta) (any, error) {
+	switch d.memStorage {
+	case StoreSerialized:
+		data, err := d.serializer.Serialize(value)
+		if err != nil {
+			return nil, err
+		}
+		d.stats.serialized(data)
+		return &serializedEntry{data: data, meta: meta}, nil
+	case StoreCopies:
+		value = deepCopy(value)
+	}
+	if meta.writtenAt.IsZero() {
+		return value, nil
+	}
+	return &memEntry[V]{value: value, meta: meta}, nil
+}
+
+// store set the item of cacheKey holding its key lock
+func (d *GoCacheDriver[V]) store(cacheKey string, stored any, ttl time.Duration) {
+	defer memKeyLocks.lock(cacheKey)()
+	d.memCache.Set(cacheKey, stored, ttl)
+}
+
+// delete the item of cacheKey holding its key lock
+func (d *GoCacheDriver[V]) delete(cacheKey string) {
+	defer memKeyLocks.lock(cacheKey)()
+	d.memCache.Delete(cacheKey)
+}
+
 func (d *GoCacheDriver[V]) Set(key string, value V, t time.Duration) error {
-	d.memCache.Set(d.getCacheKey(key), value, t)
-	return nil
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	stored, err := d.encode(value, entryMeta{})
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	d.store(cacheKey, stored, d.jitter(t))
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *GoCacheDriver[V]) SetMany(many []Many[V]) error {
-	for _, item := range many {
-		_ = d.Set(item.Key, item.Value, item.TTL)
+	keys := lo.Map(many, func(item Many[V], index int) string {
+		return item.Key
+	})
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return d.stats.fail(err)
 	}
-	return nil
+	for i, item := range many {
+		stored, err := d.encode(item.Value, entryMeta{})
+		if err != nil {
+			return d.stats.fail(fmt.Errorf("key %q: %w", item.Key, err))
+		}
+		d.store(cacheKeys[i], stored, d.jitter(item.TTL))
+	}
+	d.stats.set(len(many))
+	return d.publishInvalidation(keys...)
 }
 
 func (d *GoCacheDriver[V]) Many(keys []string) (map[string]V, error) {
+	items, notFoundKeys, err := d.many(keys)
+	if err != nil {
+		return nil, d.stats.fail(err)
+	}
+	d.stats.lookupMany(len(keys), len(items)+len(notFoundKeys))
+	return items, nil
+}
+
+// many Retrieve multiple items and the keys of the negative cached items from the cache.
+func (d *GoCacheDriver[V]) many(keys []string) (map[string]V, []string, error) {
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return nil, nil, err
+	}
 	items := make(map[string]V)
-	for _, key := range keys {
-		if value, found := d.memCache.Get(d.getCacheKey(key)); found {
-			item, ok := value.(V)
-			if !ok {
-				var zero V
-				return nil, fmt.Errorf("cache item type mismatch for key %q: expected %T, got %T", key, zero, value)
+	var notFoundKeys []string
+	for i, key := range keys {
+		if value, found := d.memCache.Get(cacheKeys[i]); found {
+			item, _, err := d.decode(value)
+			if errors.Is(err, ErrNotFound) {
+				notFoundKeys = append(notFoundKeys, key)
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("key %q: %w", key, err)
 			}
 			items[key] = item
 		}
 	}
-	return items, nil
+	return items, notFoundKeys, nil
 }
 
 func (d *GoCacheDriver[V]) DelMany(keys []string) error {
-	for _, key := range keys {
-		d.memCache.Delete(d.getCacheKey(key))
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return d.stats.fail(err)
 	}
-	return nil
+	for _, cacheKey := range cacheKeys {
+		d.delete(cacheKey)
+	}
+	d.stats.del(len(keys))
+	return d.publishInvalidation(keys...)
 }
 
 func (d *GoCacheDriver[V]) ForgetMany(keys []string) error {
@@ -55,21 +173,46 @@ func (d *GoCacheDriver[V]) ForgetMany(keys []string) error {
 }
 
 func (d *GoCacheDriver[V]) Add(key string, value V, t time.Duration) error {
-	err := d.memCache.Add(d.getCacheKey(key), value, t)
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	stored, err := d.encode(value, entryMeta{})
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	unlock := memKeyLocks.lock(cacheKey)
+	err = d.memCache.Add(cacheKey, stored, d.jitter(t))
+	unlock()
 	if err != nil {
 		return ErrCacheExisted
 	}
-	return err
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *GoCacheDriver[V]) Forever(key string, value V) error {
-	d.memCache.Set(d.getCacheKey(key), value, gocache.NoExpiration)
-	return nil
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	stored, err := d.encode(value, entryMeta{})
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	d.store(cacheKey, stored, gocache.NoExpiration)
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *GoCacheDriver[V]) Forget(key string) error {
-	d.memCache.Delete(d.getCacheKey(key))
-	return nil
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	d.delete(cacheKey)
+	d.stats.del(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *GoCacheDriver[V]) Del(key string) error {
@@ -77,83 +220,176 @@ func (d *GoCacheDriver[V]) Del(key string) error {
 }
 
 func (d *GoCacheDriver[V]) Flush() error {
-	if d.prefix != "" {
-		prefix := d.prefix + ":"
-		for key := range d.memCache.Items() {
-			if strings.HasPrefix(key, prefix) {
-				d.memCache.Delete(key)
-			}
+	if d.namespace != nil {
+		if err := d.flushNamespace(); err != nil {
+			return d.stats.fail(err)
 		}
-		return nil
+		return d.publishFlush()
 	}
-	d.memCache.Flush()
-	return nil
+	flushMemCache(d.memCache, d.prefix)
+	return d.publishFlush()
 }
 
 func (d *GoCacheDriver[V]) Get(key string) (result V, err error) {
-	if value, found := d.memCache.Get(d.getCacheKey(key)); !found {
-		return result, ErrCacheMiss
-	} else {
-		var ok bool
-		result, ok = value.(V)
-		if !ok {
-			return result, fmt.Errorf("cache item type mismatch: expected %T, got %T", result, value)
-		}
-		return result, nil
+	result, _, err = d.getEntry(key)
+	d.stats.lookup(err)
+	return
+}
+
+func (d *GoCacheDriver[V]) getEntry(key string) (result V, meta entryMeta, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, meta, err
+	}
+	value, found := d.memCache.Get(cacheKey)
+	if !found {
+		return result, meta, ErrCacheMiss
+	}
+	return d.decode(value)
+}
+
+func (d *GoCacheDriver[V]) setEntry(key string, value V, meta entryMeta, ttl time.Duration) error {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	ttl = d.jitter(ttl)
+	stored, err := d.encode(value, meta.written(ttl))
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	d.store(cacheKey, stored, ttl)
+	d.stats.set(1)
+	return d.publishInvalidation(key)
+}
+
+func (d *GoCacheDriver[V]) setTombstones(keys []string) error {
+	if d.negativeTTL <= 0 || len(keys) == 0 {
+		return nil
+	}
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return d.stats.fail(err)
 	}
+	for _, cacheKey := range cacheKeys {
+		d.store(cacheKey, tombstone{}, d.negativeTTL)
+	}
+	d.stats.set(len(keys))
+	return d.publishInvalidation(keys...)
+}
+
+// decode a value stored in go-cache, with or without metadata, ErrNotFound for tombstones
+func (d *GoCacheDriver[V]) decode(value any) (result V, meta entryMeta, err error) {
+	switch value := value.(type) {
+	case tombstone:
+		return result, meta, ErrNotFound
+	case *serializedEntry:
+		err = d.serializer.UnSerialize(value.data, &result)
+		return result, value.meta, err
+	case *memEntry[V]:
+		return d.copy(value.value), value.meta, nil
+	case V:
+		return d.copy(value), meta, nil
+	default:
+		return result, meta, fmt.Errorf("cache item type mismatch: expected %T, got %T", result, value)
+	}
+}
+
+// copy a value read from go-cache when the driver stores copies
+func (d *GoCacheDriver[V]) copy(value V) V {
+	if d.memStorage == StoreCopies {
+		return deepCopy(value)
+	}
+	return value
 }
 
 func (d *GoCacheDriver[V]) Has(key string) (bool, error) {
-	_, found := d.memCache.Get(d.getCacheKey(key))
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	value, found := d.memCache.Get(cacheKey)
+	if _, ok := value.(tombstone); ok {
+		return false, nil
+	}
 	return found, nil
 }
 
 func (d *GoCacheDriver[V]) SetNumber(key string, value V, t time.Duration) error {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
 	switch any(value).(type) {
 	case int, int8, int16, int32, int64:
-		d.memCache.Set(d.getCacheKey(key), cast.ToInt64(value), t)
+		d.store(cacheKey, cast.ToInt64(value), d.jitter(t))
 	case uint, uint8, uint16, uint32, uint64:
-		d.memCache.Set(d.getCacheKey(key), cast.ToUint64(value), t)
+		d.store(cacheKey, cast.ToUint64(value), d.jitter(t))
 	case float32, float64:
-		d.memCache.Set(d.getCacheKey(key), cast.ToFloat64(value), t)
+		d.store(cacheKey, cast.ToFloat64(value), d.jitter(t))
 	default:
 		return fmt.Errorf("the value for %v is not a number", value)
 	}
-	return nil
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *GoCacheDriver[V]) Increment(key string, n V) (ret V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return ret, d.stats.fail(err)
+	}
 	var res any
+	unlock := memKeyLocks.lock(cacheKey)
 	switch any(n).(type) {
 	case int, int8, int16, int32, int64:
-		res, err = d.memCache.IncrementInt64(d.getCacheKey(key), cast.ToInt64(n))
+		res, err = d.memCache.IncrementInt64(cacheKey, cast.ToInt64(n))
 	case uint, uint8, uint16, uint32, uint64:
-		res, err = d.memCache.IncrementUint64(d.getCacheKey(key), cast.ToUint64(n))
+		res, err = d.memCache.IncrementUint64(cacheKey, cast.ToUint64(n))
 	case float32, float64:
-		res, err = d.memCache.IncrementFloat64(d.getCacheKey(key), cast.ToFloat64(n))
+		res, err = d.memCache.IncrementFloat64(cacheKey, cast.ToFloat64(n))
 	default:
+		unlock()
 		return ret, fmt.Errorf("invalid number type: %T", n)
 	}
+	unlock()
 	if err != nil {
+		err = d.stats.fail(err)
+		return
+	}
+	d.stats.set(1)
+	if err = d.publishInvalidation(key); err != nil {
 		return
 	}
 	return toAnyE[V](res)
 }
 
 func (d *GoCacheDriver[V]) Decrement(key string, n V) (ret V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return ret, d.stats.fail(err)
+	}
 	var res any
+	unlock := memKeyLocks.lock(cacheKey)
 	switch any(n).(type) {
 	case int, int8, int16, int32, int64:
-		res, err = d.memCache.DecrementInt64(d.getCacheKey(key), cast.ToInt64(n))
+		res, err = d.memCache.DecrementInt64(cacheKey, cast.ToInt64(n))
 	case uint, uint8, uint16, uint32, uint64:
-		res, err = d.memCache.DecrementUint64(d.getCacheKey(key), cast.ToUint64(n))
+		res, err = d.memCache.DecrementUint64(cacheKey, cast.ToUint64(n))
 	case float32, float64:
-		res, err = d.memCache.DecrementFloat64(d.getCacheKey(key), cast.ToFloat64(n))
+		res, err = d.memCache.DecrementFloat64(cacheKey, cast.ToFloat64(n))
 	default:
+		unlock()
 		var res V
 		return res, fmt.Errorf("the value for %v is not a number", n)
 	}
+	unlock()
 	if err != nil {
+		err = d.stats.fail(err)
+		return
+	}
+	d.stats.set(1)
+	if err = d.publishInvalidation(key); err != nil {
 		return
 	}
 	ret, err = toAnyE[V](res)
@@ -161,16 +397,30 @@ func (d *GoCacheDriver[V]) Decrement(key string, n V) (ret V, err error) {
 }
 
 func (d *GoCacheDriver[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (result V, err error) {
+	if d.xfetch != nil {
+		return rememberEarly[V](d, key, ttl, callback, force)
+	}
 	if !force {
-		if result, err = d.Get(key); err == nil {
+		if result, err = d.Get(key); err == nil || errors.Is(err, ErrNotFound) {
 			return
 		}
 	}
-	if result, err = callback(); err != nil {
-		return
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
 	}
-	err = d.Set(key, result, ttl)
-	return
+	return doFlight(d.ctx, d.group, cacheKey, func() (V, error) {
+		result, err := load(d.stats, callback)
+		if errors.Is(err, ErrNotFound) {
+			if err := d.setTombstones([]string{key}); err != nil {
+				return result, err
+			}
+		}
+		if err != nil {
+			return result, err
+		}
+		return result, d.Set(key, result, ttl)
+	})
 }
 
 func (d *GoCacheDriver[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
@@ -184,39 +434,63 @@ func (d *GoCacheDriver[V]) RememberMany(keys []string, ttl time.Duration, callba
 	)
 	many := make(map[string]V)
 	if !force {
-		many, err = d.Many(keys)
+		var notFoundKeys []string
+		many, notFoundKeys, err = d.many(keys)
 		if err != nil {
-			return nil, err
+			return nil, d.stats.fail(err)
 		}
-		notHitKeys = lo.Without(keys, lo.Keys(many)...)
+		d.stats.lookupMany(len(keys), len(many)+len(notFoundKeys))
+		notHitKeys = lo.Without(keys, append(lo.Keys(many), notFoundKeys...)...)
 		if len(notHitKeys) == 0 {
 			return many, nil
 		}
 	} else {
 		notHitKeys = keys
 	}
-	notCacheItems, err := callback(notHitKeys)
-	if err != nil {
-		return nil, err
-	}
-	var needCacheItems []Many[V]
-	for s, v := range notCacheItems {
-		needCacheItems = append(needCacheItems, Many[V]{
-			Key:   s,
-			Value: v,
-			TTL:   ttl,
+	notCacheItems, err := doFlightMany(&d.baseDriver, notHitKeys, func(notHitKeys []string) (map[string]V, error) {
+		notCacheItems, err := load(d.stats, func() (map[string]V, error) {
+			return callback(notHitKeys)
 		})
-	}
-	err = d.SetMany(needCacheItems)
+		if err != nil {
+			return nil, err
+		}
+		var needCacheItems []Many[V]
+		for s, v := range notCacheItems {
+			needCacheItems = append(needCacheItems, Many[V]{
+				Key:   s,
+				Value: v,
+				TTL:   ttl,
+			})
+		}
+		if err = d.SetMany(needCacheItems); err != nil {
+			return nil, err
+		}
+		if err = d.setTombstones(lo.Without(notHitKeys, lo.Keys(notCacheItems)...)); err != nil {
+			return nil, err
+		}
+		return notCacheItems, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	return lo.Assign(many, notCacheItems), nil
 }
 
+func (d *GoCacheDriver[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return rememberStale[V](d, func() entryStore[V] {
+		detached := &GoCacheDriver[V]{d.baseDriver}
+		detached.ctx = detachContext(d.ctx)
+		return detached
+	}, key, freshTTL, staleTTL, callback)
+}
+
 func (d *GoCacheDriver[V]) TTL(key string) (ttl time.Duration, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return ItemNotExistedTTL, d.stats.fail(err)
+	}
 	items := d.memCache.Items()
-	if item, found := items[d.getCacheKey(key)]; found {
+	if item, found := items[cacheKey]; found {
 		if item.Expiration == 0 {
 			return NoExpirationTTL, nil
 		}
@@ -230,12 +504,356 @@ func (d *GoCacheDriver[V]) TTL(key string) (ttl time.Duration, err error) {
 	return ItemNotExistedTTL, fmt.Errorf("cached item %v not found", key)
 }
 
+// expire re-set the items of keys with ttl, a ttl of gocache.NoExpiration removes the expiration and other
+// non positive ttls remove the items, publishes the keys found and returns the keys missing from the cache
+func (d *GoCacheDriver[V]) expire(keys []string, ttl time.Duration) ([]string, error) {
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return nil, d.stats.fail(err)
+	}
+	var missing, found []string
+	for i, key := range keys {
+		if d.expireKey(cacheKeys[i], ttl) {
+			found = append(found, key)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return missing, d.publishInvalidation(found...)
+}
+
+// expireKey re-set the item of cacheKey with ttl holding its key lock, reports whether it was found
+func (d *GoCacheDriver[V]) expireKey(cacheKey string, ttl time.Duration) bool {
+	defer memKeyLocks.lock(cacheKey)()
+	value, found := d.memCache.Get(cacheKey)
+	switch {
+	case !found:
+		return false
+	case ttl > 0 || ttl == gocache.NoExpiration:
+		d.memCache.Set(cacheKey, value, d.touchedTTL(value, ttl))
+	default:
+		d.memCache.Delete(cacheKey)
+	}
+	return true
+}
+
+// touchedTTL the ttl of a re-set item, tombstones are never kept for longer than negativeTTL
+func (d *GoCacheDriver[V]) touchedTTL(value any, ttl time.Duration) time.Duration {
+	if _, ok := value.(tombstone); ok && d.negativeTTL > 0 && (ttl == gocache.NoExpiration || ttl > d.negativeTTL) {
+		return d.negativeTTL
+	}
+	return ttl
+}
+
+// touchTTL the go-cache ttl of Touch, a ttl of 0 or NoExpirationTTL removes the expiration
+func touchTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return gocache.NoExpiration
+	}
+	return ttl
+}
+
+// expireAtTTL the go-cache ttl of ExpireAt, 0 removes the items expiring in the past
+func expireAtTTL(at time.Time) time.Duration {
+	if ttl := time.Until(at); ttl > 0 {
+		return ttl
+	}
+	return 0
+}
+
+func (d *GoCacheDriver[V]) Touch(key string, ttl time.Duration) error {
+	missing, err := d.expire([]string{key}, touchTTL(ttl))
+	if err != nil {
+		return err
+	}
+	return errMissingKey(missing)
+}
+
+func (d *GoCacheDriver[V]) TouchMany(keys []string, ttl time.Duration) error {
+	missing, err := d.expire(keys, touchTTL(ttl))
+	if err != nil {
+		return err
+	}
+	return errMissingKeys(missing)
+}
+
+func (d *GoCacheDriver[V]) ExpireAt(key string, at time.Time) error {
+	missing, err := d.expire([]string{key}, expireAtTTL(at))
+	if err != nil {
+		return err
+	}
+	return errMissingKey(missing)
+}
+
+func (d *GoCacheDriver[V]) ExpireAtMany(keys []string, at time.Time) error {
+	missing, err := d.expire(keys, expireAtTTL(at))
+	if err != nil {
+		return err
+	}
+	return errMissingKeys(missing)
+}
+
+func (d *GoCacheDriver[V]) Persist(key string) error {
+	return d.Touch(key, NoExpirationTTL)
+}
+
+func (d *GoCacheDriver[V]) PersistMany(keys []string) error {
+	return d.TouchMany(keys, NoExpirationTTL)
+}
+
+func (d *GoCacheDriver[V]) GetAndTouch(key string, ttl time.Duration) (result V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	unlock := memKeyLocks.lock(cacheKey)
+	value, found := d.memCache.Get(cacheKey)
+	touched := false
+	if !found {
+		err = ErrCacheMiss
+	} else if result, _, err = d.decode(value); err == nil || errors.Is(err, ErrNotFound) {
+		d.memCache.Set(cacheKey, value, d.touchedTTL(value, touchTTL(ttl)))
+		touched = true
+	}
+	unlock()
+	d.stats.lookup(err)
+	if touched {
+		if publishErr := d.publishInvalidation(key); publishErr != nil {
+			return result, publishErr
+		}
+	}
+	return
+}
+
+func (d *GoCacheDriver[V]) Pull(key string) (result V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	unlock := memKeyLocks.lock(cacheKey)
+	value, found := d.memCache.Get(cacheKey)
+	if found {
+		d.memCache.Delete(cacheKey)
+	}
+	unlock()
+	if !found {
+		d.stats.lookup(ErrCacheMiss)
+		return result, ErrCacheMiss
+	}
+	result, _, err = d.decode(value)
+	d.stats.lookup(err)
+	d.stats.del(1)
+	if pubErr := d.publishInvalidation(key); pubErr != nil {
+		return result, pubErr
+	}
+	return result, err
+}
+
+func (d *GoCacheDriver[V]) GetSet(key string, value V, t time.Duration) (result V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	stored, err := d.encode(value, entryMeta{})
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	unlock := memKeyLocks.lock(cacheKey)
+	old, found := d.memCache.Get(cacheKey)
+	d.memCache.Set(cacheKey, stored, d.jitter(t))
+	unlock()
+	d.stats.set(1)
+	err = ErrCacheMiss
+	if found {
+		result, _, err = d.decode(old)
+	}
+	d.stats.lookup(err)
+	if pubErr := d.publishInvalidation(key); pubErr != nil {
+		return result, pubErr
+	}
+	return result, err
+}
+
+func (d *GoCacheDriver[V]) CompareAndSwap(key string, old, value V, t time.Duration) (bool, error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	expected, err := d.serializer.Serialize(old)
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	stored, err := d.encode(value, entryMeta{})
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	unlock := memKeyLocks.lock(cacheKey)
+	swapped, err := d.compareAndSet(cacheKey, expected, stored, t)
+	unlock()
+	if err != nil || !swapped {
+		return false, err
+	}
+	d.stats.set(1)
+	return true, d.publishInvalidation(key)
+}
+
+// compareAndSet store the item of cacheKey if the serialized bytes of the current one are expected, its key
+// lock must be held
+func (d *GoCacheDriver[V]) compareAndSet(cacheKey string, expected []byte, stored any, t time.Duration) (bool, error) {
+	current, found := d.memCache.Get(cacheKey)
+	if !found {
+		return false, nil
+	}
+	actual, err := d.serialized(current)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		return false, nil
+	}
+	d.memCache.Set(cacheKey, stored, d.jitter(t))
+	return true, nil
+}
+
+// serialized the serialized bytes of a value stored in go-cache, ErrNotFound for tombstones
+func (d *GoCacheDriver[V]) serialized(value any) ([]byte, error) {
+	if entry, ok := value.(*serializedEntry); ok {
+		return entry.data, nil
+	}
+	result, _, err := d.decode(value)
+	if err != nil {
+		return nil, err
+	}
+	return d.serializer.Serialize(result)
+}
+
+func (d *GoCacheDriver[V]) Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (result V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	// fn runs without the key lock, the item is stored only if it was not modified meanwhile
+	for i := 0; i < maxWatchRetries; i++ {
+		current, expiration, found := d.memCache.GetWithExpiration(cacheKey)
+		var (
+			old      V
+			snapshot []byte
+		)
+		if found {
+			if old, _, err = d.decode(current); err != nil {
+				return result, d.stats.fail(err)
+			}
+			if snapshot, err = d.serialized(current); err != nil {
+				return result, d.stats.fail(err)
+			}
+		}
+		if result, err = fn(old, found); err != nil {
+			return result, err
+		}
+		stored, err := d.encode(result, entryMeta{})
+		if err != nil {
+			return result, d.stats.fail(err)
+		}
+		unlock := memKeyLocks.lock(cacheKey)
+		swapped := d.unchanged(cacheKey, found, expiration, snapshot)
+		if swapped {
+			d.memCache.Set(cacheKey, stored, d.jitter(t))
+		}
+		unlock()
+		if swapped {
+			d.stats.set(1)
+			return result, d.publishInvalidation(key)
+		}
+	}
+	return result, ErrCacheConflict
+}
+
+// unchanged report whether the item of cacheKey is still the one read by Update, its key lock must be held
+func (d *GoCacheDriver[V]) unchanged(cacheKey string, found bool, expiration time.Time, snapshot []byte) bool {
+	current, currentExpiration, currentFound := d.memCache.GetWithExpiration(cacheKey)
+	if !found || !currentFound {
+		return found == currentFound
+	}
+	if !currentExpiration.Equal(expiration) {
+		return false
+	}
+	actual, err := d.serialized(current)
+	return err == nil && bytes.Equal(actual, snapshot)
+}
+
+func (d *GoCacheDriver[V]) Scan(pattern string, batchSize int, fn func(key string) error) error {
+	return d.scan(pattern, batchSize, func(key string, _ gocache.Item) error {
+		return fn(key)
+	})
+}
+
+func (d *GoCacheDriver[V]) ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error {
+	return d.scan(pattern, batchSize, func(key string, item gocache.Item) error {
+		value, _, err := d.decode(item.Object)
+		var decryptErr *DecryptError
+		if errors.As(err, &decryptErr) {
+			return fmt.Errorf("key %q: %w", key, err)
+		}
+		if err != nil {
+			return nil
+		}
+		ttl := NoExpirationTTL
+		if item.Expiration > 0 {
+			ttl = time.Until(time.Unix(0, item.Expiration))
+		}
+		return fn(key, value, ttl)
+	})
+}
+
+// scan call fn with the unexpired items matching pattern, the context is checked every batchSize items
+func (d *GoCacheDriver[V]) scan(pattern string, batchSize int, fn func(key string, item gocache.Item) error) error {
+	if pattern == "" {
+		pattern = "*"
+	}
+	if batchSize <= 0 {
+		batchSize = defaultScanBatchSize
+	}
+	prefix, err := d.scanPrefix()
+	if err != nil {
+		return err
+	}
+	scanned := 0
+	for cacheKey, item := range d.memCache.Items() {
+		key, ok := scanKey(prefix, cacheKey)
+		if !ok || !globMatch(pattern, key) {
+			continue
+		}
+		if scanned%batchSize == 0 {
+			if err := d.ctx.Err(); err != nil {
+				return err
+			}
+		}
+		scanned++
+		if err := fn(key, item); err != nil {
+			if errors.Is(err, ErrStopScan) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *GoCacheDriver[V]) Tags(tags ...string) Driver[V] {
+	return newTaggedDriver[V](d, tags)
+}
+
 func (d *GoCacheDriver[V]) WithCtx(ctx context.Context) Driver[V] {
-	d.ctx = ctx
-	return d
+	clone := &GoCacheDriver[V]{d.baseDriver}
+	clone.ctx = ctx
+	return clone
 }
 
 func (d *GoCacheDriver[V]) WithSerializer(serializer Serializer) Driver[V] {
-	d.serializer = serializer
-	return d
+	clone := &GoCacheDriver[V]{d.baseDriver}
+	clone.serializer = serializer
+	return clone
 }
@@ -15,20 +15,18 @@ func setupGoCacheDriver[V any](t *testing.T) *GoCacheDriver[V] {
 	return setupGoCacheDriverWithPrefix[V](t, "cache_prefix")
 }
 
-func setupGoCacheDriverWithPrefix[V any](t *testing.T, prefix string) *GoCacheDriver[V] {
+func setupGoCacheDriverWithPrefix[V any](t *testing.T, prefix string, optionFns ...OptionFunc) *GoCacheDriver[V] {
 	t.Helper()
 
 	memCache := gocache.New(5*time.Minute, 10*time.Minute)
 	driverName := nextDriverName("mem_test")
-	err := RegisterGoCacheDriver(driverName, memCache, prefix)
+	err := RegisterGoCacheDriver(driverName, memCache, prefix, optionFns...)
 	require.NoError(t, err, "register go-cache driver")
 
 	driver, err := Use[V](driverName)
 	require.NoError(t, err, "use go-cache driver")
 
-	driver.WithCtx(context.Background())
-	driver.WithSerializer(&JSONSerializer{})
-	return driver.(*GoCacheDriver[V])
+	return driver.WithCtx(context.Background()).WithSerializer(&JSONSerializer{}).(*GoCacheDriver[V])
 }
 
 func TestGoCacheDriver(t *testing.T) {
@@ -123,3 +121,94 @@ func TestGoCacheComplexNumberOperationsReturnError(t *testing.T) {
 	_, err = driver.Decrement(key, value)
 	assert.Error(t, err)
 }
+
+func TestGoCacheRememberConcurrency(t *testing.T) {
+	testRememberConcurrency(t, setupGoCacheDriver[string](t))
+}
+
+func TestGoCacheRememberStale(t *testing.T) {
+	testRememberStale(t, setupGoCacheDriver[string](t))
+}
+
+func TestGoCacheNegativeCache(t *testing.T) {
+	testNegativeCache(t, setupGoCacheDriverWithPrefix[string](t, "cache_prefix", WithNegativeTTL(2*time.Second)))
+}
+
+func TestGoCacheStoreSerialized(t *testing.T) {
+	driver := setupGoCacheDriverWithPrefix[mutableItem](t, "cache_prefix", WithMemoryStorage(StoreSerialized))
+	testIndependentValues(t, driver)
+
+	stored, found := driver.memCache.Get("cache_prefix:key")
+	assert.True(t, found)
+	assert.IsType(t, &serializedEntry{}, stored)
+	stats, err := GetStats(driver.name)
+	assert.NoError(t, err)
+	assert.NotZero(t, stats.BytesSerialized)
+}
+
+func TestGoCacheStoreCopies(t *testing.T) {
+	testIndependentValues(t, setupGoCacheDriverWithPrefix[mutableItem](t, "cache_prefix", WithMemoryStorage(StoreCopies)))
+	testCache[[]int](t, setupGoCacheDriverWithPrefix[[]int](t, "cache_prefix", WithMemoryStorage(StoreCopies)), "slice", []int{1, 2, 3})
+}
+
+func TestGoCacheStoreValuesSharesValues(t *testing.T) {
+	driver := setupGoCacheDriver[mutableItem](t)
+	assert.NoError(t, driver.Set("key", newMutableItem(), time.Minute))
+
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	mutate(got)
+
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "mutated", got.Tags[0])
+}
+
+func TestGoCacheStoreSerializedKeepsNumbers(t *testing.T) {
+	driver := setupGoCacheDriverWithPrefix[int](t, "cache_prefix", WithMemoryStorage(StoreSerialized))
+	testNumberCache[int](t, driver, "number", 10)
+}
+
+func TestWithMemoryStorageRejectsInvalidStorage(t *testing.T) {
+	err := RegisterGoCacheDriver(nextDriverName("mem_test"), gocache.New(time.Minute, time.Minute), "", WithMemoryStorage(MemoryStorage(42)))
+	assert.Error(t, err)
+}
+
+func TestGoCacheConcurrentWithCtx(t *testing.T) {
+	testConcurrentWithCtx(t, setupGoCacheDriver[string](t))
+}
+
+func TestGoCacheTouch(t *testing.T) {
+	testTouch(t, setupGoCacheDriver[string](t))
+}
+
+func TestGoCacheAtomicOperations(t *testing.T) {
+	testAtomicOperations(t, setupGoCacheDriver[int](t))
+	testAtomicOperations(t, setupGoCacheDriverWithPrefix[int](t, "serialized", WithMemoryStorage(StoreSerialized)))
+}
+
+func TestGoCacheUpdateRetriesOnConcurrentWrite(t *testing.T) {
+	driver := setupGoCacheDriver[string](t)
+	var calls []string
+	got, err := driver.Update("key", time.Minute, func(old string, found bool) (string, error) {
+		calls = append(calls, old)
+		if len(calls) == 1 {
+			// the callback may use the driver, the write of the key makes Update call it again
+			assert.NoError(t, driver.Set("key", "set", time.Minute))
+		}
+		return old + "+updated", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "set+updated", got)
+	assert.Equal(t, []string{"", "set"}, calls)
+
+	_, err = driver.Update("key", time.Minute, func(old string, found bool) (string, error) {
+		assert.NoError(t, driver.Set("key", old+"!", time.Minute))
+		return old, nil
+	})
+	assert.ErrorIs(t, err, ErrCacheConflict)
+}
+
+func TestGoCacheTouchCapsTombstones(t *testing.T) {
+	testTouchCapsTombstones(t, setupGoCacheDriverWithPrefix[string](t, "cache_prefix", WithNegativeTTL(time.Minute)))
+}
@@ -1,10 +1,13 @@
 package cacheit
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -18,63 +21,157 @@ type RedisDriver[V any] struct {
 }
 
 func (d *RedisDriver[V]) Set(key string, value V, t time.Duration) error {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
 	serialize, err := d.serializer.Serialize(value)
 	if err != nil {
-		return err
+		return d.stats.fail(err)
 	}
+	d.stats.serialized(serialize)
 
-	return d.redisClient.Set(d.ctx, d.getCacheKey(key), string(serialize), t).Err()
+	if err = d.redisClient.Set(d.ctx, cacheKey, string(serialize), d.jitter(t)).Err(); err != nil {
+		return d.stats.fail(err)
+	}
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *RedisDriver[V]) SetMany(many []Many[V]) error {
 	if len(many) == 0 {
 		return nil
 	}
-	pipeline := d.redisClient.Pipeline()
-	defer pipeline.Close()
+	keys := make([]string, 0, len(many))
+	values := make([]string, 0, len(many))
 	for _, m := range many {
 		serialize, err := d.serializer.Serialize(m.Value)
 		if err != nil {
-			return err
+			return d.stats.fail(err)
 		}
-		pipeline.Set(d.ctx, d.getCacheKey(m.Key), string(serialize), normalizeTTL(m.TTL))
+		d.stats.serialized(serialize)
+		keys = append(keys, m.Key)
+		values = append(values, string(serialize))
 	}
-	_, err := pipeline.Exec(d.ctx)
-	return err
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	err = d.chunked(keys, func(start, end int) error {
+		// pipelines of cluster and ring clients send every command to the node of its key
+		_, err := d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+			for i := start; i < end; i++ {
+				pipe.Set(d.ctx, cacheKeys[i], values[i], d.jitter(normalizeTTL(many[i].TTL)))
+			}
+			return nil
+		})
+		return err
+	})
+	return d.wrote(keys, err, d.stats.set)
+}
+
+// wrote record the written keys of a batch write which returned err, all of them but the keys of a
+// *ChunkError failed if err is not nil. Every key is published since the failed writes may be partially applied.
+func (d *RedisDriver[V]) wrote(keys []string, err error, record func(n int)) error {
+	written := keys
+	var chunkErr *ChunkError
+	switch {
+	case errors.As(err, &chunkErr):
+		written = lo.Without(keys, chunkErr.Keys...)
+	case err != nil:
+		written = nil
+	}
+	record(len(written))
+	if pubErr := d.publishInvalidation(keys...); pubErr != nil && err == nil {
+		return pubErr
+	}
+	return d.stats.fail(err)
 }
 
 func (d *RedisDriver[V]) Many(keys []string) (map[string]V, error) {
+	results, notFoundKeys, err := d.many(keys)
+	if err != nil {
+		return results, d.stats.fail(err)
+	}
+	d.stats.lookupMany(len(keys), len(results)+len(notFoundKeys))
+	return results, nil
+}
+
+// many Retrieve multiple items and the keys of the negative cached items from the cache, the items of
+// the chunks which succeeded are returned with a *ChunkError.
+func (d *RedisDriver[V]) many(keys []string) (map[string]V, []string, error) {
+	return d.manyTTL(keys, nil)
+}
+
+// manyTTL is many which also reads the remaining ttl of the items found into ttls when it is not nil.
+func (d *RedisDriver[V]) manyTTL(keys []string, ttls map[string]time.Duration) (map[string]V, []string, error) {
 	results := make(map[string]V)
 	if len(keys) == 0 {
-		return results, nil
+		return results, nil, nil
 	}
-	cacheKeys := d.getCacheKeys(keys)
-	result, err := d.redisClient.MGet(d.ctx, cacheKeys...).Result()
+	cacheKeys, err := d.getCacheKeys(keys)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	result := make([]any, len(keys))
+	var pttls []time.Duration
+	if ttls != nil {
+		pttls = make([]time.Duration, len(keys))
+	}
+	chunkErr := d.chunked(keys, func(start, end int) error {
+		var chunkTTLs []time.Duration
+		if pttls != nil {
+			chunkTTLs = pttls[start:end]
+		}
+		values, err := d.mget(cacheKeys[start:end], chunkTTLs)
+		copy(result[start:end], values)
+		return err
+	})
+	if chunkErr != nil && !errors.As(chunkErr, new(*ChunkError)) {
+		return nil, nil, chunkErr
 	}
+	var notFoundKeys []string
 	for i, r := range result {
 		if r == nil {
 			continue
 		}
+		data := []byte(cast.ToString(r))
+		if isTombstone(data) {
+			notFoundKeys = append(notFoundKeys, keys[i])
+			continue
+		}
 		var v V
-		err = d.serializer.UnSerialize([]byte(cast.ToString(r)), &v)
+		_, payload := decodeEntry(data)
+		err := d.serializer.UnSerialize(payload, &v)
+		var decryptErr *DecryptError
+		if errors.As(err, &decryptErr) {
+			return nil, nil, fmt.Errorf("key %q: %w", keys[i], err)
+		}
 		if err != nil {
 			continue
 		}
 
 		results[keys[i]] = v
+		if ttls != nil {
+			ttls[keys[i]] = pttls[i]
+		}
 	}
 
-	return results, nil
+	return results, notFoundKeys, chunkErr
 }
 
 func (d *RedisDriver[V]) DelMany(keys []string) error {
 	if len(keys) == 0 {
 		return nil
 	}
-	cacheKeys := d.getCacheKeys(keys)
-	return d.redisClient.Del(d.ctx, cacheKeys...).Err()
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	err = d.chunked(keys, func(start, end int) error {
+		return d.delKeys(d.ctx, d.redisClient, cacheKeys[start:end])
+	})
+	return d.wrote(keys, err, d.stats.del)
 }
 
 func (d *RedisDriver[V]) ForgetMany(keys []string) error {
@@ -82,30 +179,53 @@ func (d *RedisDriver[V]) ForgetMany(keys []string) error {
 }
 
 func (d *RedisDriver[V]) Add(key string, value V, t time.Duration) error {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
 	serialize, err := d.serializer.Serialize(value)
 	if err != nil {
-		return err
+		return d.stats.fail(err)
 	}
-	res, err := d.redisClient.SetNX(d.ctx, d.getCacheKey(key), string(serialize), t).Result()
+	d.stats.serialized(serialize)
+	res, err := d.redisClient.SetNX(d.ctx, cacheKey, string(serialize), d.jitter(t)).Result()
 	if err != nil {
-		return err
+		return d.stats.fail(err)
 	}
 	if !res {
 		return ErrCacheExisted
 	}
-	return nil
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *RedisDriver[V]) Forever(key string, value V) error {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
 	serialize, err := d.serializer.Serialize(value)
 	if err != nil {
-		return err
+		return d.stats.fail(err)
+	}
+	d.stats.serialized(serialize)
+	if err = d.redisClient.Set(d.ctx, cacheKey, string(serialize), 0).Err(); err != nil {
+		return d.stats.fail(err)
 	}
-	return d.redisClient.Set(d.ctx, d.getCacheKey(key), string(serialize), 0).Err()
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *RedisDriver[V]) Forget(key string) error {
-	return d.redisClient.Del(d.ctx, d.getCacheKey(key)).Err()
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	if err := d.redisClient.Del(d.ctx, cacheKey).Err(); err != nil {
+		return d.stats.fail(err)
+	}
+	d.stats.del(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *RedisDriver[V]) Del(key string) error {
@@ -113,68 +233,184 @@ func (d *RedisDriver[V]) Del(key string) error {
 }
 
 func (d *RedisDriver[V]) Flush() error {
+	if d.namespace != nil {
+		if err := d.flushNamespace(); err != nil {
+			return d.stats.fail(err)
+		}
+		return d.publishFlush()
+	}
 	if d.prefix != "" {
-		var cursor uint64
-		pattern := d.prefix + ":*"
-		for {
-			keys, nextCursor, err := d.redisClient.Scan(d.ctx, cursor, pattern, 0).Result()
-			if err != nil {
-				return err
-			}
-			if len(keys) > 0 {
-				if err := d.redisClient.Del(d.ctx, keys...).Err(); err != nil {
-					return err
-				}
-			}
-			if nextCursor == 0 {
-				return nil
-			}
-			cursor = nextCursor
+		if err := d.deleteMatching(d.prefix + ":*"); err != nil {
+			return d.stats.fail(err)
 		}
+		return d.publishFlush()
+	}
+	err := d.forEachNode(func(ctx context.Context, client redis.Cmdable) error {
+		return client.FlushDB(ctx).Err()
+	})
+	if err != nil {
+		return d.stats.fail(err)
 	}
-	return d.redisClient.FlushDB(d.ctx).Err()
+	return d.publishFlush()
 }
 
 func (d *RedisDriver[V]) Get(key string) (V, error) {
+	result, _, err := d.getEntry(key)
+	d.stats.lookup(err)
+	return result, err
+}
+
+func (d *RedisDriver[V]) getEntry(key string) (V, entryMeta, error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		var result V
+		return result, entryMeta{}, err
+	}
+	return d.decode(d.redisClient.Get(d.ctx, cacheKey).Bytes())
+}
+
+// getEntryTTL Retrieve an item, its metadata and its remaining ttl in a single pipeline
+func (d *RedisDriver[V]) getEntryTTL(key string) (V, entryMeta, time.Duration, error) {
+	var (
+		result V
+		get    *redis.StringCmd
+		pttl   *redis.DurationCmd
+	)
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, entryMeta{}, 0, err
+	}
+	_, err = d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(d.ctx, cacheKey)
+		pttl = pipe.PTTL(d.ctx, cacheKey)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return result, entryMeta{}, 0, err
+	}
+	result, meta, err := d.decode(get.Bytes())
+	return result, meta, pttl.Val(), err
+}
+
+// decode the reply of a GET, ErrCacheMiss for missing items and ErrNotFound for tombstones
+func (d *RedisDriver[V]) decode(value []byte, err error) (V, entryMeta, error) {
 	var result V
-	if value, err := d.redisClient.Get(d.ctx, d.getCacheKey(key)).Result(); err != nil {
+	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return result, ErrCacheMiss
+			return result, entryMeta{}, ErrCacheMiss
 		}
-		return result, err
-	} else {
-		err = d.serializer.UnSerialize([]byte(value), &result)
-		return result, err
+		return result, entryMeta{}, err
 	}
+	if isTombstone(value) {
+		return result, entryMeta{}, ErrNotFound
+	}
+	meta, payload := decodeEntry(value)
+	err = d.serializer.UnSerialize(payload, &result)
+	return result, meta, err
+}
+
+func (d *RedisDriver[V]) setEntry(key string, value V, meta entryMeta, ttl time.Duration) error {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	serialize, err := d.serializer.Serialize(value)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	d.stats.serialized(serialize)
+	ttl = d.jitter(normalizeTTL(ttl))
+	if err = d.redisClient.Set(d.ctx, cacheKey, encodeEntry(meta.written(ttl), serialize), ttl).Err(); err != nil {
+		return d.stats.fail(err)
+	}
+	d.stats.set(1)
+	return d.publishInvalidation(key)
+}
+
+func (d *RedisDriver[V]) setTombstones(keys []string) error {
+	if d.negativeTTL <= 0 || len(keys) == 0 {
+		return nil
+	}
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	err = d.chunked(keys, func(start, end int) error {
+		_, err := d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+			for _, cacheKey := range cacheKeys[start:end] {
+				pipe.Set(d.ctx, cacheKey, tombstoneMagic, d.negativeTTL)
+			}
+			return nil
+		})
+		return err
+	})
+	return d.wrote(keys, err, d.stats.set)
 }
 
 func (d *RedisDriver[V]) Has(key string) (bool, error) {
-	result, err := d.redisClient.Exists(d.ctx, d.getCacheKey(key)).Result()
+	cacheKey, err := d.getCacheKey(key)
 	if err != nil {
-		return false, err
+		return false, d.stats.fail(err)
 	}
-	return result > 0, err
+	if d.negativeTTL <= 0 {
+		result, err := d.redisClient.Exists(d.ctx, cacheKey).Result()
+		if err != nil {
+			return false, d.stats.fail(err)
+		}
+		return result > 0, err
+	}
+	// tombstones are reported as missing
+	var (
+		exists *redis.IntCmd
+		head   *redis.StringCmd
+	)
+	_, err = d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(d.ctx, cacheKey)
+		head = pipe.GetRange(d.ctx, cacheKey, 0, int64(len(tombstoneMagic)))
+		return nil
+	})
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	return exists.Val() > 0 && !isTombstone([]byte(head.Val())), nil
 }
 
 func (d *RedisDriver[V]) SetNumber(key string, value V, t time.Duration) error {
 	if !isNumeric(value) {
 		return fmt.Errorf("the value for %v is not a number", value)
 	}
-	return d.redisClient.Set(d.ctx, d.getCacheKey(key), value, t).Err()
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return d.stats.fail(err)
+	}
+	if err := d.redisClient.Set(d.ctx, cacheKey, value, d.jitter(t)).Err(); err != nil {
+		return d.stats.fail(err)
+	}
+	d.stats.set(1)
+	return d.publishInvalidation(key)
 }
 
 func (d *RedisDriver[V]) Increment(key string, n V) (ret V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return ret, d.stats.fail(err)
+	}
 	var res any
 	switch reflect.TypeOf(n).Name() {
 	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
-		res, err = d.redisClient.IncrBy(d.ctx, d.getCacheKey(key), cast.ToInt64(n)).Result()
+		res, err = d.redisClient.IncrBy(d.ctx, cacheKey, cast.ToInt64(n)).Result()
 	case "float32", "float64":
-		res, err = d.redisClient.IncrByFloat(d.ctx, d.getCacheKey(key), cast.ToFloat64(n)).Result()
+		res, err = d.redisClient.IncrByFloat(d.ctx, cacheKey, cast.ToFloat64(n)).Result()
 	default:
 		var res V
 		return res, fmt.Errorf("the value for %v is not a number", n)
 	}
 	if err != nil {
+		err = d.stats.fail(err)
+		return
+	}
+	d.stats.set(1)
+	if err = d.publishInvalidation(key); err != nil {
 		return
 	}
 	ret, err = toAnyE[V](res)
@@ -182,17 +418,26 @@ func (d *RedisDriver[V]) Increment(key string, n V) (ret V, err error) {
 }
 
 func (d *RedisDriver[V]) Decrement(key string, n V) (ret V, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return ret, d.stats.fail(err)
+	}
 	var res any
 	switch reflect.TypeOf(n).Name() {
 	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
-		res, err = d.redisClient.DecrBy(d.ctx, d.getCacheKey(key), cast.ToInt64(n)).Result()
+		res, err = d.redisClient.DecrBy(d.ctx, cacheKey, cast.ToInt64(n)).Result()
 	case "float32", "float64":
-		res, err = d.redisClient.IncrByFloat(d.ctx, d.getCacheKey(key), 0-cast.ToFloat64(n)).Result()
+		res, err = d.redisClient.IncrByFloat(d.ctx, cacheKey, 0-cast.ToFloat64(n)).Result()
 	default:
 		var res V
 		return res, fmt.Errorf("the value for %v is not a number", n)
 	}
 	if err != nil {
+		err = d.stats.fail(err)
+		return
+	}
+	d.stats.set(1)
+	if err = d.publishInvalidation(key); err != nil {
 		return
 	}
 	ret, err = toAnyE[V](res)
@@ -200,16 +445,30 @@ func (d *RedisDriver[V]) Decrement(key string, n V) (ret V, err error) {
 }
 
 func (d *RedisDriver[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (result V, err error) {
+	if d.xfetch != nil {
+		return rememberEarly[V](d, key, ttl, callback, force)
+	}
 	if !force {
-		if result, err = d.Get(key); err == nil {
+		if result, err = d.Get(key); err == nil || errors.Is(err, ErrNotFound) {
 			return
 		}
 	}
-	if result, err = callback(); err != nil {
-		return
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
 	}
-	err = d.Set(key, result, ttl)
-	return
+	return doFlight(d.ctx, d.group, cacheKey, func() (V, error) {
+		result, err := load(d.stats, callback)
+		if errors.Is(err, ErrNotFound) {
+			if err := d.setTombstones([]string{key}); err != nil {
+				return result, err
+			}
+		}
+		if err != nil {
+			return result, err
+		}
+		return result, d.Set(key, result, ttl)
+	})
 }
 
 func (d *RedisDriver[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
@@ -223,48 +482,506 @@ func (d *RedisDriver[V]) RememberMany(keys []string, ttl time.Duration, callback
 	)
 	many := make(map[string]V)
 	if !force {
-		many, err = d.Many(keys)
-		if err != nil {
-			return nil, err
+		var notFoundKeys []string
+		many, notFoundKeys, err = d.many(keys)
+		// the keys of the failed chunks are loaded like the missing ones
+		if err != nil && !errors.As(err, new(*ChunkError)) {
+			return nil, d.stats.fail(err)
 		}
-		notHitKeys = lo.Without(keys, lo.Keys(many)...)
+		_ = d.stats.fail(err)
+		d.stats.lookupMany(len(keys), len(many)+len(notFoundKeys))
+		notHitKeys = lo.Without(keys, append(lo.Keys(many), notFoundKeys...)...)
 		if len(notHitKeys) == 0 {
 			return many, nil
 		}
 	} else {
 		notHitKeys = keys
 	}
-	notCacheItems, err := callback(notHitKeys)
+	notCacheItems, err := doFlightMany(&d.baseDriver, notHitKeys, func(notHitKeys []string) (map[string]V, error) {
+		notCacheItems, err := load(d.stats, func() (map[string]V, error) {
+			return callback(notHitKeys)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var needCacheItems []Many[V]
+		for s, v := range notCacheItems {
+			needCacheItems = append(needCacheItems, Many[V]{
+				Key:   s,
+				Value: v,
+				TTL:   ttl,
+			})
+		}
+		if err = d.SetMany(needCacheItems); err != nil {
+			return nil, err
+		}
+		if err = d.setTombstones(lo.Without(notHitKeys, lo.Keys(notCacheItems)...)); err != nil {
+			return nil, err
+		}
+		return notCacheItems, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	var needCacheItems []Many[V]
-	for s, v := range notCacheItems {
-		needCacheItems = append(needCacheItems, Many[V]{
-			Key:   s,
-			Value: v,
-			TTL:   ttl,
+	return lo.Assign(many, notCacheItems), nil
+}
+
+func (d *RedisDriver[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return rememberStale[V](d, func() entryStore[V] {
+		detached := &RedisDriver[V]{d.baseDriver}
+		detached.ctx = detachContext(d.ctx)
+		return detached
+	}, key, freshTTL, staleTTL, callback)
+}
+
+func (d *RedisDriver[V]) TTL(key string) (ttl time.Duration, err error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return 0, d.stats.fail(err)
+	}
+	ttl, err = d.redisClient.TTL(d.ctx, cacheKey).Result()
+	return ttl, d.stats.fail(err)
+}
+
+// touchScript set the ttl of KEYS[1] with the command ARGV[1] and its argument ARGV[2], the tombstone ARGV[4]
+// is never kept for longer than the negative ttl ARGV[3] in milliseconds, ARGV[5] is the client time of
+// PEXPIREAT. Replies the item if ARGV[6] is set, whether it exists otherwise.
+var touchScript = redis.NewScript(`
+local kind = redis.call("TYPE", KEYS[1]).ok
+if kind == "none" then
+	if ARGV[6] == "1" then
+		return false
+	end
+	return 0
+end
+local command, arg, negative = ARGV[1], tonumber(ARGV[2]), tonumber(ARGV[3])
+local value
+if kind == "string" and (ARGV[6] == "1" or redis.call("STRLEN", KEYS[1]) == #ARGV[4]) then
+	value = redis.call("GET", KEYS[1])
+end
+if negative > 0 and value == ARGV[4] then
+	local ttl = arg
+	if command == "PEXPIREAT" then
+		ttl = arg - tonumber(ARGV[5])
+	end
+	if command == "PERSIST" or ttl > negative then
+		command, arg = "PEXPIRE", negative
+	end
+end
+if command == "PERSIST" then
+	redis.call("PERSIST", KEYS[1])
+else
+	redis.call(command, KEYS[1], arg)
+end
+if ARGV[6] == "1" then
+	return value
+end
+return 1`)
+
+// touchArgs the arguments of touchScript, a ttl of 0 or NoExpirationTTL removes the expiration
+func (d *RedisDriver[V]) touchArgs(ttl time.Duration, get bool) []any {
+	if ttl <= 0 {
+		return d.expireArgs("PERSIST", 0, get)
+	}
+	return d.expireArgs("PEXPIRE", ttl.Milliseconds(), get)
+}
+
+// expireArgs the arguments of touchScript running the ttl command with arg
+func (d *RedisDriver[V]) expireArgs(command string, arg int64, get bool) []any {
+	return []any{command, arg, d.negativeTTL.Milliseconds(), tombstoneMagic, time.Now().UnixMilli(), get}
+}
+
+// expire run touchScript with args on each key, publishes the keys found and returns the keys missing from the cache
+func (d *RedisDriver[V]) expire(keys []string, args []any) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return nil, d.stats.fail(err)
+	}
+	var cmds []*redis.Cmd
+	// the script is sent again with its source if redis does not know it yet, the ttl commands are idempotent
+	for _, eval := range []func(pipe redis.Pipeliner, key string) *redis.Cmd{
+		func(pipe redis.Pipeliner, key string) *redis.Cmd {
+			return touchScript.EvalSha(d.ctx, pipe, []string{key}, args...)
+		},
+		func(pipe redis.Pipeliner, key string) *redis.Cmd {
+			return touchScript.Eval(d.ctx, pipe, []string{key}, args...)
+		},
+	} {
+		cmds = make([]*redis.Cmd, len(keys))
+		_, err := d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+			for i, cacheKey := range cacheKeys {
+				cmds[i] = eval(pipe, cacheKey)
+			}
+			return nil
+		})
+		if err == nil {
+			break
+		}
+		if !lo.ContainsBy(cmds, func(cmd *redis.Cmd) bool {
+			return isNoScript(cmd.Err())
+		}) {
+			return nil, d.stats.fail(err)
+		}
+	}
+	var missing, found []string
+	for i, cmd := range cmds {
+		if exists, err := cmd.Int(); err != nil {
+			return nil, d.stats.fail(err)
+		} else if exists == 0 {
+			missing = append(missing, keys[i])
+		} else {
+			found = append(found, keys[i])
+		}
+	}
+	return missing, d.publishInvalidation(found...)
+}
+
+// isNoScript report whether err is the reply of EVALSHA for a script unknown to redis
+func isNoScript(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT ")
+}
+
+func (d *RedisDriver[V]) Touch(key string, ttl time.Duration) error {
+	missing, err := d.expire([]string{key}, d.touchArgs(ttl, false))
+	if err != nil {
+		return err
+	}
+	return errMissingKey(missing)
+}
+
+func (d *RedisDriver[V]) TouchMany(keys []string, ttl time.Duration) error {
+	missing, err := d.expire(keys, d.touchArgs(ttl, false))
+	if err != nil {
+		return err
+	}
+	return errMissingKeys(missing)
+}
+
+func (d *RedisDriver[V]) ExpireAt(key string, at time.Time) error {
+	missing, err := d.expire([]string{key}, d.expireArgs("PEXPIREAT", at.UnixMilli(), false))
+	if err != nil {
+		return err
+	}
+	return errMissingKey(missing)
+}
+
+func (d *RedisDriver[V]) ExpireAtMany(keys []string, at time.Time) error {
+	missing, err := d.expire(keys, d.expireArgs("PEXPIREAT", at.UnixMilli(), false))
+	if err != nil {
+		return err
+	}
+	return errMissingKeys(missing)
+}
+
+func (d *RedisDriver[V]) Persist(key string) error {
+	return d.Touch(key, NoExpirationTTL)
+}
+
+func (d *RedisDriver[V]) PersistMany(keys []string) error {
+	return d.TouchMany(keys, NoExpirationTTL)
+}
+
+func (d *RedisDriver[V]) GetAndTouch(key string, ttl time.Duration) (V, error) {
+	result, _, err := d.getAndTouch(key, ttl)
+	d.stats.lookup(err)
+	return result, err
+}
+
+// getAndTouch Retrieve an item and its metadata and set its ttl atomically
+func (d *RedisDriver[V]) getAndTouch(key string, ttl time.Duration) (V, entryMeta, error) {
+	var result V
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, entryMeta{}, err
+	}
+	cmd := touchScript.Run(d.ctx, d.redisClient, []string{cacheKey}, d.touchArgs(ttl, true)...)
+	value, err := cmd.Text()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return result, entryMeta{}, err
+	}
+	if err == nil {
+		if err := d.publishInvalidation(key); err != nil {
+			return result, entryMeta{}, err
+		}
+	}
+	return d.decode([]byte(value), err)
+}
+
+func (d *RedisDriver[V]) Pull(key string) (V, error) {
+	var result V
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	var get *redis.StringCmd
+	_, err = d.redisClient.TxPipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(d.ctx, cacheKey)
+		pipe.Del(d.ctx, cacheKey)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return result, d.stats.fail(err)
+	}
+	result, _, err = d.decode(get.Bytes())
+	d.stats.lookup(err)
+	if get.Err() == nil {
+		d.stats.del(1)
+		if pubErr := d.publishInvalidation(key); pubErr != nil {
+			return result, pubErr
+		}
+	}
+	return result, err
+}
+
+func (d *RedisDriver[V]) GetSet(key string, value V, t time.Duration) (V, error) {
+	var result V
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	serialize, err := d.serializer.Serialize(value)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	d.stats.serialized(serialize)
+	var get *redis.StringCmd
+	_, err = d.redisClient.TxPipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(d.ctx, cacheKey)
+		pipe.Set(d.ctx, cacheKey, string(serialize), d.jitter(normalizeTTL(t)))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return result, d.stats.fail(err)
+	}
+	d.stats.set(1)
+	result, _, err = d.decode(get.Bytes())
+	d.stats.lookup(err)
+	if pubErr := d.publishInvalidation(key); pubErr != nil {
+		return result, pubErr
+	}
+	return result, err
+}
+
+func (d *RedisDriver[V]) CompareAndSwap(key string, old, value V, t time.Duration) (bool, error) {
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	expected, err := d.serializer.Serialize(old)
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	serialize, err := d.serializer.Serialize(value)
+	if err != nil {
+		return false, d.stats.fail(err)
+	}
+	d.stats.serialized(serialize)
+	swapped := false
+	err = d.watch(cacheKey, func(tx *redis.Tx) error {
+		current, err := tx.Get(d.ctx, cacheKey).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, payload := decodeEntry(current); isTombstone(current) || !bytes.Equal(payload, expected) {
+			return nil
+		}
+		_, err = tx.TxPipelined(d.ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(d.ctx, cacheKey, string(serialize), d.jitter(normalizeTTL(t)))
+			return nil
 		})
+		swapped = err == nil
+		return err
+	})
+	if err != nil || !swapped {
+		return false, d.stats.fail(err)
 	}
-	err = d.SetMany(needCacheItems)
+	d.stats.set(1)
+	return true, d.publishInvalidation(key)
+}
+
+func (d *RedisDriver[V]) Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (result V, err error) {
+	cacheKey, err := d.getCacheKey(key)
 	if err != nil {
-		return nil, err
+		return result, d.stats.fail(err)
 	}
-	return lo.Assign(many, notCacheItems), nil
+	var callbackErr error
+	err = d.watch(cacheKey, func(tx *redis.Tx) error {
+		old, _, err := d.decode(tx.Get(d.ctx, cacheKey).Bytes())
+		if err != nil && !errors.Is(err, ErrCacheMiss) {
+			return err
+		}
+		if result, callbackErr = fn(old, err == nil); callbackErr != nil {
+			return callbackErr
+		}
+		serialize, err := d.serializer.Serialize(result)
+		if err != nil {
+			return err
+		}
+		d.stats.serialized(serialize)
+		_, err = tx.TxPipelined(d.ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(d.ctx, cacheKey, string(serialize), d.jitter(normalizeTTL(t)))
+			return nil
+		})
+		return err
+	})
+	if callbackErr != nil {
+		return result, callbackErr
+	}
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	d.stats.set(1)
+	return result, d.publishInvalidation(key)
 }
 
-func (d *RedisDriver[V]) TTL(key string) (ttl time.Duration, err error) {
-	return d.redisClient.TTL(d.ctx, d.getCacheKey(key)).Result()
+// watch run fn in a WATCH transaction on cacheKey, fn is retried while cacheKey is modified by other clients
+func (d *RedisDriver[V]) watch(cacheKey string, fn func(tx *redis.Tx) error) error {
+	for i := 0; i < maxWatchRetries; i++ {
+		err := d.redisClient.Watch(d.ctx, fn, cacheKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return ErrCacheConflict
+}
+
+func (d *RedisDriver[V]) Scan(pattern string, batchSize int, fn func(key string) error) error {
+	return d.scan(pattern, batchSize, func(_ context.Context, _ redis.Cmdable, _ []string, keys []string) error {
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *RedisDriver[V]) ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error {
+	return d.scan(pattern, batchSize, func(ctx context.Context, client redis.Cmdable, cacheKeys []string, keys []string) error {
+		gets := make([]*redis.StringCmd, len(cacheKeys))
+		ttls := make([]*redis.DurationCmd, len(cacheKeys))
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, cacheKey := range cacheKeys {
+				gets[i] = pipe.Get(ctx, cacheKey)
+				ttls[i] = pipe.PTTL(ctx, cacheKey)
+			}
+			return nil
+		})
+		// the replies of redis are checked command by command below
+		var redisErr redis.Error
+		if err != nil && !errors.As(err, &redisErr) {
+			return err
+		}
+		for i, key := range keys {
+			// the keys removed since the SCAN and those of other types, e.g. hashes, are skipped
+			if err := gets[i].Err(); errors.Is(err, redis.Nil) || isWrongType(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err := ttls[i].Err(); err != nil {
+				return err
+			}
+			value, _, err := d.decode(gets[i].Bytes())
+			var decryptErr *DecryptError
+			if errors.As(err, &decryptErr) {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			if err != nil {
+				continue
+			}
+			if err = fn(key, value, ttls[i].Val()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isWrongType report whether err is the reply of redis to a command run against a key of another type
+func isWrongType(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(redisErr.Error(), "WRONGTYPE ")
+}
+
+// scan call fn with the batches of cache keys matching pattern and their keys, SCAN runs on every master of
+// a cluster concurrently, the calls of fn are serialized
+func (d *RedisDriver[V]) scan(pattern string, batchSize int, fn func(ctx context.Context, client redis.Cmdable, cacheKeys []string, keys []string) error) error {
+	if pattern == "" {
+		pattern = "*"
+	}
+	if batchSize <= 0 {
+		batchSize = defaultScanBatchSize
+	}
+	prefix, err := d.scanPrefix()
+	if err != nil {
+		return err
+	}
+	match := escapeGlob(prefix) + pattern
+	var (
+		mu      sync.Mutex
+		stopped bool
+	)
+	err = d.forEachNode(func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			cacheKeys, nextCursor, err := client.Scan(ctx, cursor, match, int64(batchSize)).Result()
+			if err != nil {
+				return err
+			}
+			var batch, keys []string
+			for _, cacheKey := range cacheKeys {
+				if key, ok := scanKey(prefix, cacheKey); ok {
+					batch, keys = append(batch, cacheKey), append(keys, key)
+				}
+			}
+			if len(keys) > 0 {
+				mu.Lock()
+				if stopped {
+					err = ErrStopScan
+				} else if err = fn(ctx, client, batch, keys); errors.Is(err, ErrStopScan) {
+					stopped = true
+				}
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			if nextCursor == 0 {
+				return nil
+			}
+			cursor = nextCursor
+		}
+	})
+	if errors.Is(err, ErrStopScan) {
+		return nil
+	}
+	return err
+}
+
+func (d *RedisDriver[V]) Tags(tags ...string) Driver[V] {
+	return newTaggedDriver[V](d, tags)
 }
 
 func (d *RedisDriver[V]) WithCtx(ctx context.Context) Driver[V] {
-	d.ctx = ctx
-	return d
+	clone := &RedisDriver[V]{d.baseDriver}
+	clone.ctx = ctx
+	return clone
 }
 
 func (d *RedisDriver[V]) WithSerializer(serializer Serializer) Driver[V] {
-	d.serializer = serializer
-	return d
+	clone := &RedisDriver[V]{d.baseDriver}
+	clone.serializer = serializer
+	return clone
 }
 
 func normalizeTTL(ttl time.Duration) time.Duration {
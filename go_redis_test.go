@@ -25,7 +25,7 @@ func setupRedisDriver[V any](t *testing.T) *RedisDriver[V] {
 	return setupRedisDriverWithPrefix[V](t, "cache_prefix")
 }
 
-func setupRedisDriverWithPrefix[V any](t *testing.T, prefix string) *RedisDriver[V] {
+func setupRedisDriverWithPrefix[V any](t *testing.T, prefix string, optionFns ...OptionFunc) *RedisDriver[V] {
 	t.Helper()
 
 	mr, err := miniredis.Run()
@@ -41,15 +41,13 @@ func setupRedisDriverWithPrefix[V any](t *testing.T, prefix string) *RedisDriver
 	})
 
 	driverName := nextDriverName("redis_test")
-	err = RegisterRedisDriver(driverName, client, prefix)
+	err = RegisterRedisDriver(driverName, client, prefix, optionFns...)
 	require.NoError(t, err, "register redis driver")
 
 	driver, err := Use[V](driverName)
 	require.NoError(t, err, "use redis driver")
 
-	driver.WithCtx(context.Background())
-	driver.WithSerializer(&JSONSerializer{})
-	return driver.(*RedisDriver[V])
+	return driver.WithCtx(context.Background()).WithSerializer(&JSONSerializer{}).(*RedisDriver[V])
 }
 
 func TestRedisDriver(t *testing.T) {
@@ -215,3 +213,85 @@ func TestDefaultDriverNameConcurrentAccess(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestRedisRememberConcurrency(t *testing.T) {
+	testRememberConcurrency(t, setupRedisDriver[string](t))
+}
+
+func TestRedisRememberStale(t *testing.T) {
+	driver := setupRedisDriver[string](t)
+	testRememberStale(t, driver)
+
+	_, err := driver.RememberStale("swr_ttl", time.Minute, time.Hour, func() (string, error) {
+		return "value", nil
+	})
+	assert.NoError(t, err)
+	ttl, err := driver.TTL("swr_ttl")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute+time.Hour, ttl)
+}
+
+func TestRedisNegativeCache(t *testing.T) {
+	testNegativeCache(t, setupRedisDriverWithPrefix[string](t, "cache_prefix", WithNegativeTTL(2*time.Second)))
+}
+
+func TestRedisIndependentValues(t *testing.T) {
+	testIndependentValues(t, setupRedisDriver[mutableItem](t))
+}
+
+func TestRedisConcurrentWithCtx(t *testing.T) {
+	testConcurrentWithCtx(t, setupRedisDriver[string](t))
+}
+
+func TestRedisWithCtxDoesNotLeakCancellation(t *testing.T) {
+	driver := setupRedisDriver[string](t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := driver.WithCtx(ctx).Get("key")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+}
+
+func TestRedisTouch(t *testing.T) {
+	testTouch(t, setupRedisDriver[string](t))
+}
+
+func TestRedisTouchCapsTombstones(t *testing.T) {
+	testTouchCapsTombstones(t, setupRedisDriverWithPrefix[string](t, "cache_prefix", WithNegativeTTL(time.Minute)))
+}
+
+func TestRedisAtomicOperations(t *testing.T) {
+	testAtomicOperations(t, setupRedisDriver[int](t))
+}
+
+func TestRedisUpdateRetriesConcurrentWrites(t *testing.T) {
+	driver := setupRedisDriver[int](t)
+	assert.NoError(t, driver.Set("key", 1, time.Minute))
+
+	calls := 0
+	got, err := driver.Update("key", time.Minute, func(old int, found bool) (int, error) {
+		calls++
+		if calls == 1 {
+			assert.NoError(t, driver.Set("key", 10, time.Minute))
+		}
+		return old + 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, got)
+	assert.Equal(t, 2, calls)
+
+	_, err = driver.Update("key", time.Minute, func(old int, found bool) (int, error) {
+		return old, driver.Set("key", old+1, time.Minute)
+	})
+	assert.ErrorIs(t, err, ErrCacheConflict)
+}
+
+func TestRedisCompareAndSwapEntryWithMetadata(t *testing.T) {
+	driver := setupRedisDriver[int](t)
+	assert.NoError(t, driver.setEntry("key", 1, entryMeta{writtenAt: time.Now(), ttl: time.Minute}, time.Minute))
+
+	swapped, err := driver.CompareAndSwap("key", 1, 2, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+}
@@ -0,0 +1,375 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+)
+
+// HashDriver cache of hashes, whose fields are read and written one by one and expire together
+type HashDriver[F any] interface {
+	// HGet Retrieve a field of a hash, ErrCacheMiss is returned if the hash or the field does not exist.
+	HGet(key string, field string) (F, error)
+	// HSet Store a field of a hash, the ttl of the hash is kept, a new hash does not expire.
+	HSet(key string, field string, value F) error
+	// HSetMany Store multiple fields of a hash, the ttl of the hash is kept, a new hash does not expire.
+	HSetMany(key string, fields map[string]F) error
+	// HGetAll Retrieve all the fields of a hash, empty if the hash does not exist.
+	HGetAll(key string) (map[string]F, error)
+	// HIncrBy Increment the integer field of a hash by n, a missing field counts as 0.
+	// The field is stored as a decimal integer whatever the serializer.
+	HIncrBy(key string, field string, n int64) (int64, error)
+	// HDel Remove fields of a hash, the hash is removed with its last field.
+	HDel(key string, fields ...string) error
+	// Expire Set the ttl of a whole hash, a ttl of 0 or NoExpirationTTL removes the expiration.
+	// ErrCacheMiss is returned if the hash does not exist.
+	Expire(key string, ttl time.Duration) error
+	// TTL Get the ttl of a hash
+	TTL(key string) (time.Duration, error)
+	// Del Remove a whole hash.
+	Del(key string) error
+	// WithCtx Get a copy of the driver using ctx, the driver itself is not modified.
+	WithCtx(ctx context.Context) HashDriver[F]
+	// WithSerializer Get a copy of the driver using serializer to store the fields, the driver itself is not modified.
+	WithSerializer(serializer Serializer) HashDriver[F]
+}
+
+// UseHash select a driver for hashes whose fields are of type F, the tiered driver stores the hashes
+// in redis only. The middlewares and hooks of the driver do not apply to the hashes.
+func UseHash[F any](driverName string) (HashDriver[F], error) {
+	value, ok := registerDrivers.Load(driverName)
+	if !ok {
+		return nil, fmt.Errorf("cached driver: %s not registered", driverName)
+	}
+	baseDriver := *value.(*baseDriver)
+	switch baseDriver.driverType {
+	case driverRedis, driverTiered:
+		return &RedisHashDriver[F]{baseDriver}, nil
+	case driverMemory:
+		return &GoCacheHashDriver[F]{baseDriver}, nil
+	default:
+		return nil, fmt.Errorf("unsupport driver type: %s", baseDriver.driverType)
+	}
+}
+
+// RedisHashDriver redis hashes driver implemented, the fields are stored serialized
+type RedisHashDriver[F any] struct {
+	baseDriver
+}
+
+func (h *RedisHashDriver[F]) HGet(key string, field string) (result F, err error) {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return result, h.stats.fail(err)
+	}
+	data, err := h.redisClient.HGet(h.ctx, cacheKey, field).Bytes()
+	if errors.Is(err, redis.Nil) {
+		err = ErrCacheMiss
+	} else if err == nil {
+		err = h.serializer.UnSerialize(data, &result)
+	}
+	h.stats.lookup(err)
+	return result, err
+}
+
+func (h *RedisHashDriver[F]) HSet(key string, field string, value F) error {
+	return h.HSetMany(key, map[string]F{field: value})
+}
+
+func (h *RedisHashDriver[F]) HSetMany(key string, fields map[string]F) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(fields))
+	for field, value := range fields {
+		serialize, err := h.serializer.Serialize(value)
+		if err != nil {
+			return h.stats.fail(fmt.Errorf("field %q: %w", field, err))
+		}
+		h.stats.serialized(serialize)
+		values[field] = string(serialize)
+	}
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	if err := h.redisClient.HSet(h.ctx, cacheKey, values).Err(); err != nil {
+		return h.stats.fail(err)
+	}
+	h.stats.set(1)
+	return nil
+}
+
+func (h *RedisHashDriver[F]) HGetAll(key string) (map[string]F, error) {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return nil, h.stats.fail(err)
+	}
+	values, err := h.redisClient.HGetAll(h.ctx, cacheKey).Result()
+	if err != nil {
+		return nil, h.stats.fail(err)
+	}
+	results := make(map[string]F, len(values))
+	for field, value := range values {
+		var result F
+		if err = h.serializer.UnSerialize([]byte(value), &result); err != nil {
+			return nil, h.stats.fail(fmt.Errorf("field %q: %w", field, err))
+		}
+		results[field] = result
+	}
+	h.stats.lookupMany(1, lo.Ternary(len(results) > 0, 1, 0))
+	return results, nil
+}
+
+func (h *RedisHashDriver[F]) HIncrBy(key string, field string, n int64) (int64, error) {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return 0, h.stats.fail(err)
+	}
+	result, err := h.redisClient.HIncrBy(h.ctx, cacheKey, field, n).Result()
+	if err != nil {
+		return 0, h.stats.fail(err)
+	}
+	h.stats.set(1)
+	return result, nil
+}
+
+func (h *RedisHashDriver[F]) HDel(key string, fields ...string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	deleted, err := h.redisClient.HDel(h.ctx, cacheKey, fields...).Result()
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	h.stats.del(int(deleted))
+	return nil
+}
+
+func (h *RedisHashDriver[F]) Expire(key string, ttl time.Duration) error {
+	return (&RedisDriver[F]{h.baseDriver}).Touch(key, ttl)
+}
+
+func (h *RedisHashDriver[F]) TTL(key string) (time.Duration, error) {
+	return (&RedisDriver[F]{h.baseDriver}).TTL(key)
+}
+
+func (h *RedisHashDriver[F]) Del(key string) error {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	if err := h.redisClient.Del(h.ctx, cacheKey).Err(); err != nil {
+		return h.stats.fail(err)
+	}
+	h.stats.del(1)
+	return nil
+}
+
+func (h *RedisHashDriver[F]) WithCtx(ctx context.Context) HashDriver[F] {
+	clone := &RedisHashDriver[F]{h.baseDriver}
+	clone.ctx = ctx
+	return clone
+}
+
+func (h *RedisHashDriver[F]) WithSerializer(serializer Serializer) HashDriver[F] {
+	clone := &RedisHashDriver[F]{h.baseDriver}
+	clone.serializer = serializer
+	return clone
+}
+
+// memHash a hash stored in go-cache, its fields are stored serialized like in redis and modified in place
+// under the mutex of its cache key, so the expiration of the hash is kept
+type memHash struct {
+	fields map[string][]byte
+}
+
+// GoCacheHashDriver go-cache hashes driver implemented
+type GoCacheHashDriver[F any] struct {
+	baseDriver
+}
+
+// hash the hash stored at cacheKey, nil if there is none and create is false, memKeyLocks must be held
+func (h *GoCacheHashDriver[F]) hash(cacheKey string, create bool) (*memHash, error) {
+	value, found := h.memCache.Get(cacheKey)
+	if !found {
+		if !create {
+			return nil, nil
+		}
+		hash := &memHash{fields: make(map[string][]byte)}
+		h.memCache.Set(cacheKey, hash, gocache.NoExpiration)
+		return hash, nil
+	}
+	hash, ok := value.(*memHash)
+	if !ok {
+		return nil, fmt.Errorf("cache item type mismatch: expected hash, got %T", value)
+	}
+	return hash, nil
+}
+
+func (h *GoCacheHashDriver[F]) HGet(key string, field string) (result F, err error) {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return result, h.stats.fail(err)
+	}
+	defer memKeyLocks.lock(cacheKey)()
+	hash, err := h.hash(cacheKey, false)
+	if err != nil {
+		return result, h.stats.fail(err)
+	}
+	err = ErrCacheMiss
+	if hash != nil {
+		if data, found := hash.fields[field]; found {
+			err = h.serializer.UnSerialize(data, &result)
+		}
+	}
+	h.stats.lookup(err)
+	return result, err
+}
+
+func (h *GoCacheHashDriver[F]) HSet(key string, field string, value F) error {
+	return h.HSetMany(key, map[string]F{field: value})
+}
+
+func (h *GoCacheHashDriver[F]) HSetMany(key string, fields map[string]F) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	values := make(map[string][]byte, len(fields))
+	for field, value := range fields {
+		serialize, err := h.serializer.Serialize(value)
+		if err != nil {
+			return h.stats.fail(fmt.Errorf("field %q: %w", field, err))
+		}
+		h.stats.serialized(serialize)
+		values[field] = serialize
+	}
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	defer memKeyLocks.lock(cacheKey)()
+	hash, err := h.hash(cacheKey, true)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	for field, value := range values {
+		hash.fields[field] = value
+	}
+	h.stats.set(1)
+	return nil
+}
+
+func (h *GoCacheHashDriver[F]) HGetAll(key string) (map[string]F, error) {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return nil, h.stats.fail(err)
+	}
+	defer memKeyLocks.lock(cacheKey)()
+	hash, err := h.hash(cacheKey, false)
+	if err != nil {
+		return nil, h.stats.fail(err)
+	}
+	results := make(map[string]F)
+	if hash == nil {
+		h.stats.lookupMany(1, 0)
+		return results, nil
+	}
+	for field, data := range hash.fields {
+		var result F
+		if err = h.serializer.UnSerialize(data, &result); err != nil {
+			return nil, h.stats.fail(fmt.Errorf("field %q: %w", field, err))
+		}
+		results[field] = result
+	}
+	h.stats.lookupMany(1, 1)
+	return results, nil
+}
+
+func (h *GoCacheHashDriver[F]) HIncrBy(key string, field string, n int64) (int64, error) {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return 0, h.stats.fail(err)
+	}
+	defer memKeyLocks.lock(cacheKey)()
+	hash, err := h.hash(cacheKey, true)
+	if err != nil {
+		return 0, h.stats.fail(err)
+	}
+	var current int64
+	if data, found := hash.fields[field]; found {
+		if current, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return 0, h.stats.fail(fmt.Errorf("hash field %q is not an integer", field))
+		}
+	}
+	current += n
+	hash.fields[field] = []byte(strconv.FormatInt(current, 10))
+	h.stats.set(1)
+	return current, nil
+}
+
+func (h *GoCacheHashDriver[F]) HDel(key string, fields ...string) error {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	defer memKeyLocks.lock(cacheKey)()
+	hash, err := h.hash(cacheKey, false)
+	if err != nil || hash == nil {
+		return h.stats.fail(err)
+	}
+	deleted := 0
+	for _, field := range fields {
+		if _, found := hash.fields[field]; found {
+			delete(hash.fields, field)
+			deleted++
+		}
+	}
+	if len(hash.fields) == 0 {
+		h.memCache.Delete(cacheKey)
+	}
+	h.stats.del(deleted)
+	return nil
+}
+
+func (h *GoCacheHashDriver[F]) Expire(key string, ttl time.Duration) error {
+	// Touch holds the key lock
+	return (&GoCacheDriver[F]{h.baseDriver}).Touch(key, ttl)
+}
+
+func (h *GoCacheHashDriver[F]) TTL(key string) (time.Duration, error) {
+	return (&GoCacheDriver[F]{h.baseDriver}).TTL(key)
+}
+
+func (h *GoCacheHashDriver[F]) Del(key string) error {
+	cacheKey, err := h.getCacheKey(key)
+	if err != nil {
+		return h.stats.fail(err)
+	}
+	defer memKeyLocks.lock(cacheKey)()
+	h.memCache.Delete(cacheKey)
+	h.stats.del(1)
+	return nil
+}
+
+func (h *GoCacheHashDriver[F]) WithCtx(ctx context.Context) HashDriver[F] {
+	clone := &GoCacheHashDriver[F]{h.baseDriver}
+	clone.ctx = ctx
+	return clone
+}
+
+func (h *GoCacheHashDriver[F]) WithSerializer(serializer Serializer) HashDriver[F] {
+	clone := &GoCacheHashDriver[F]{h.baseDriver}
+	clone.serializer = serializer
+	return clone
+}
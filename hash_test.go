@@ -0,0 +1,137 @@
+package cacheit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHash(t *testing.T, driverName string) {
+	hash, err := UseHash[int](driverName)
+	require.NoError(t, err)
+
+	_, err = hash.HGet("user", "age")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	fields, err := hash.HGetAll("user")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+	assert.ErrorIs(t, hash.Expire("user", time.Hour), ErrCacheMiss)
+
+	assert.NoError(t, hash.HSet("user", "age", 30))
+	assert.NoError(t, hash.HSetMany("user", map[string]int{"height": 180, "weight": 70}))
+	got, err := hash.HGet("user", "age")
+	assert.NoError(t, err)
+	assert.Equal(t, 30, got)
+	_, err = hash.HGet("user", "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	fields, err = hash.HGetAll("user")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"age": 30, "height": 180, "weight": 70}, fields)
+
+	ttl, err := hash.TTL("user")
+	assert.NoError(t, err)
+	assert.Equal(t, NoExpirationTTL, ttl)
+	assert.NoError(t, hash.Expire("user", time.Hour))
+	assert.NoError(t, hash.HSet("user", "age", 31))
+	ttl, err = hash.TTL("user")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Hour-time.Minute, "writing a field keeps the ttl of the hash")
+
+	n, err := hash.HIncrBy("user", "age", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(33), n)
+	n, err = hash.HIncrBy("user", "visits", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), n)
+	got, err = hash.HGet("user", "visits")
+	assert.NoError(t, err)
+	assert.Equal(t, -1, got)
+
+	names, err := UseHash[string](driverName)
+	require.NoError(t, err)
+	assert.NoError(t, names.HSet("names", "first", "john"))
+	_, err = names.HIncrBy("names", "first", 1)
+	assert.Error(t, err)
+	other, err := names.WithSerializer(&MsgpackSerializer{}).HGetAll("names")
+	assert.Error(t, err, "the fields are stored with the serializer")
+	assert.Nil(t, other)
+	assert.NoError(t, names.Del("names"))
+	first, err := names.WithSerializer(&MsgpackSerializer{}).HGet("names", "first")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Empty(t, first)
+
+	deletes := func() uint64 {
+		stats, err := GetStats(driverName)
+		require.NoError(t, err)
+		return stats.Deletes
+	}
+	before := deletes()
+	assert.NoError(t, hash.HDel("user", "age", "height", "missing"))
+	assert.Equal(t, before+2, deletes(), "only the deleted fields are counted")
+	fields, err = hash.HGetAll("user")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"weight": 70, "visits": -1}, fields)
+	assert.NoError(t, hash.HDel("user", "weight", "visits"))
+	assert.ErrorIs(t, hash.Expire("user", time.Hour), ErrCacheMiss, "the hash is removed with its last field")
+	assert.NoError(t, hash.HDel("user", "age"))
+	assert.Equal(t, before+4, deletes(), "nothing is deleted from a missing hash")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := hash.HIncrBy("counters", "hits", 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	got, err = hash.HGet("counters", "hits")
+	assert.NoError(t, err)
+	assert.Equal(t, 20, got)
+}
+
+func TestRedisHash(t *testing.T) {
+	driver := setupRedisDriver[int](t)
+	testHash(t, driver.name)
+
+	hash, err := UseHash[int](driver.name)
+	require.NoError(t, err)
+	assert.NoError(t, hash.HSet("key", "field", 1))
+	value, err := driver.redisClient.HGet(driver.ctx, "cache_prefix:key", "field").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+}
+
+func TestGoCacheHash(t *testing.T) {
+	driver := setupGoCacheDriver[int](t)
+	testHash(t, driver.name)
+
+	hash, err := UseHash[int](driver.name)
+	require.NoError(t, err)
+	assert.NoError(t, hash.HSet("key", "field", 1))
+	_, found := driver.memCache.Get("cache_prefix:key")
+	assert.True(t, found)
+
+	assert.NoError(t, driver.Set("plain", 1, time.Minute))
+	_, err = hash.HGet("plain", "field")
+	assert.Error(t, err)
+}
+
+func TestTieredHash(t *testing.T) {
+	driver := setupTieredDriver[int](t, time.Minute)
+	testHash(t, driver.name)
+
+	hash, err := UseHash[int](driver.name)
+	require.NoError(t, err)
+	_, ok := hash.(*RedisHashDriver[int])
+	assert.True(t, ok)
+}
+
+func TestUseHashUnknownDriver(t *testing.T) {
+	_, err := UseHash[int]("non_existing_driver")
+	assert.Error(t, err)
+}
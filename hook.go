@@ -0,0 +1,374 @@
+package cacheit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// HookEvent describes a cache operation to the hooks
+type HookEvent struct {
+	// Operation name of the Driver method, e.g. Get, SetMany, Remember
+	Operation string
+	// DriverName name the driver was registered with
+	DriverName string
+	// DriverType type of the driver
+	DriverType DriverType
+	// Key key of the single key operations, hashed if the driver was registered WithHashedHookKeys
+	Key string
+	// Count number of items of the operation
+	Count int
+	// Hits items found in the cache by the read operations, Remember* included
+	Hits int
+	// Misses items not found in the cache by the read operations, Remember* included
+	Misses int
+	// Err error of the operation, cache misses and existed items are not errors
+	Err error
+}
+
+// Hook invoked around every operation of the drivers registered WithHooks
+type Hook interface {
+	// BeforeOperation called before the operation with the context of the driver, the operation runs
+	// with the returned context, which is passed to AfterOperation.
+	BeforeOperation(ctx context.Context, event *HookEvent) context.Context
+	// AfterOperation called after the operation with its outcome.
+	AfterOperation(ctx context.Context, event *HookEvent)
+}
+
+// hashHookKey the key reported to the hooks by the drivers registered WithHashedHookKeys
+func hashHookKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// lookup record the outcome of a single key read
+func (e *HookEvent) lookup(err error) {
+	if err == nil || errors.Is(err, ErrNotFound) {
+		e.Hits = 1
+	} else if errors.Is(err, ErrCacheMiss) {
+		e.Misses = 1
+	}
+}
+
+// hookedDriver a driver whose operations are reported to the hooks of the registration
+type hookedDriver[V any] struct {
+	DriverWrapper[V]
+}
+
+func newHookedDriver[V any](driver Driver[V]) Driver[V] {
+	return &hookedDriver[V]{DriverWrapper[V]{Next: driver, Rewrap: newHookedDriver[V]}}
+}
+
+// run fn between the hooks, hooks are called before the operation in their order and after it in reverse order.
+// fn runs the operation on next, bound to the context returned by the hooks.
+func (h *hookedDriver[V]) run(event *HookEvent, fn func(next Driver[V]) error) error {
+	d := h.base()
+	event.DriverName, event.DriverType = d.name, d.driverType
+	if d.hashHookKeys && event.Key != "" {
+		event.Key = hashHookKey(event.Key)
+	}
+	ctxs := make([]context.Context, len(d.hooks))
+	ctx := d.ctx
+	for i, hook := range d.hooks {
+		ctx = hook.BeforeOperation(ctx, event)
+		ctxs[i] = ctx
+	}
+	next := h.Next
+	if ctx != d.ctx {
+		next = next.WithCtx(ctx)
+	}
+	err := fn(next)
+	if err != nil && !errors.Is(err, ErrCacheMiss) && !errors.Is(err, ErrCacheExisted) {
+		event.Err = err
+	}
+	for i := len(d.hooks) - 1; i >= 0; i-- {
+		d.hooks[i].AfterOperation(ctxs[i], event)
+	}
+	return err
+}
+
+// runKey run a single key operation
+func (h *hookedDriver[V]) runKey(op string, key string, fn func(next Driver[V]) error) error {
+	return h.run(&HookEvent{Operation: op, Key: key, Count: 1}, fn)
+}
+
+// runKeys run a multiple keys operation
+func (h *hookedDriver[V]) runKeys(op string, count int, fn func(next Driver[V]) error) error {
+	return h.run(&HookEvent{Operation: op, Count: count}, fn)
+}
+
+// remember run a Remember* operation, the item is a miss if the callback is executed or the load of another
+// caller is shared
+func remember[V any](h *hookedDriver[V], op string, key string, callback func() (V, error), fn func(next Driver[V], callback func() (V, error)) (V, error)) (result V, err error) {
+	var loaded int32
+	var shared int64
+	event := &HookEvent{Operation: op, Key: key, Count: 1}
+	err = h.run(event, func(next Driver[V]) error {
+		next = next.WithCtx(withSharedLoads(next.(baseProvider).base().ctx, &shared))
+		result, err = fn(next, func() (V, error) {
+			atomic.StoreInt32(&loaded, 1)
+			return callback()
+		})
+		if atomic.LoadInt32(&loaded) == 1 || atomic.LoadInt64(&shared) > 0 {
+			event.Misses = 1
+		} else {
+			event.lookup(err)
+		}
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Add(key string, value V, t time.Duration) error {
+	return h.runKey("Add", key, func(next Driver[V]) error {
+		return next.Add(key, value, t)
+	})
+}
+
+func (h *hookedDriver[V]) Set(key string, value V, t time.Duration) error {
+	return h.runKey("Set", key, func(next Driver[V]) error {
+		return next.Set(key, value, t)
+	})
+}
+
+func (h *hookedDriver[V]) SetMany(many []Many[V]) error {
+	return h.runKeys("SetMany", len(many), func(next Driver[V]) error {
+		return next.SetMany(many)
+	})
+}
+
+func (h *hookedDriver[V]) Forever(key string, value V) error {
+	return h.runKey("Forever", key, func(next Driver[V]) error {
+		return next.Forever(key, value)
+	})
+}
+
+func (h *hookedDriver[V]) Forget(key string) error {
+	return h.runKey("Forget", key, func(next Driver[V]) error {
+		return next.Forget(key)
+	})
+}
+
+func (h *hookedDriver[V]) Del(key string) error {
+	return h.runKey("Del", key, func(next Driver[V]) error {
+		return next.Del(key)
+	})
+}
+
+func (h *hookedDriver[V]) Flush() error {
+	return h.runKeys("Flush", 0, Driver[V].Flush)
+}
+
+func (h *hookedDriver[V]) Get(key string) (result V, err error) {
+	event := &HookEvent{Operation: "Get", Key: key, Count: 1}
+	err = h.run(event, func(next Driver[V]) error {
+		result, err = next.Get(key)
+		event.lookup(err)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Has(key string) (found bool, err error) {
+	event := &HookEvent{Operation: "Has", Key: key, Count: 1}
+	err = h.run(event, func(next Driver[V]) error {
+		found, err = next.Has(key)
+		if err == nil {
+			event.Hits = lo.Ternary(found, 1, 0)
+			event.Misses = 1 - event.Hits
+		}
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Many(keys []string) (items map[string]V, err error) {
+	event := &HookEvent{Operation: "Many", Count: len(keys)}
+	err = h.run(event, func(next Driver[V]) error {
+		items, err = next.Many(keys)
+		if err == nil {
+			event.Hits = len(items)
+			event.Misses = len(keys) - len(items)
+		}
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) DelMany(keys []string) error {
+	return h.runKeys("DelMany", len(keys), func(next Driver[V]) error {
+		return next.DelMany(keys)
+	})
+}
+
+func (h *hookedDriver[V]) ForgetMany(keys []string) error {
+	return h.runKeys("ForgetMany", len(keys), func(next Driver[V]) error {
+		return next.ForgetMany(keys)
+	})
+}
+
+func (h *hookedDriver[V]) SetNumber(key string, value V, t time.Duration) error {
+	return h.runKey("SetNumber", key, func(next Driver[V]) error {
+		return next.SetNumber(key, value, t)
+	})
+}
+
+func (h *hookedDriver[V]) Increment(key string, n V) (ret V, err error) {
+	err = h.runKey("Increment", key, func(next Driver[V]) error {
+		ret, err = next.Increment(key, n)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Decrement(key string, n V) (ret V, err error) {
+	err = h.runKey("Decrement", key, func(next Driver[V]) error {
+		ret, err = next.Decrement(key, n)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (V, error) {
+	return remember(h, "Remember", key, callback, func(next Driver[V], callback func() (V, error)) (V, error) {
+		return next.Remember(key, ttl, callback, force)
+	})
+}
+
+func (h *hookedDriver[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
+	return remember(h, "RememberForever", key, callback, func(next Driver[V], callback func() (V, error)) (V, error) {
+		return next.RememberForever(key, callback, force)
+	})
+}
+
+func (h *hookedDriver[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return remember(h, "RememberStale", key, callback, func(next Driver[V], callback func() (V, error)) (V, error) {
+		return next.RememberStale(key, freshTTL, staleTTL, callback)
+	})
+}
+
+func (h *hookedDriver[V]) RememberMany(keys []string, ttl time.Duration, callback func(notHitKeys []string) (map[string]V, error), force bool) (items map[string]V, err error) {
+	// the keys loaded by the callback or by another caller are misses
+	var misses int64
+	event := &HookEvent{Operation: "RememberMany", Count: len(keys)}
+	err = h.run(event, func(next Driver[V]) error {
+		next = next.WithCtx(withSharedLoads(next.(baseProvider).base().ctx, &misses))
+		items, err = next.RememberMany(keys, ttl, func(notHitKeys []string) (map[string]V, error) {
+			atomic.AddInt64(&misses, int64(len(notHitKeys)))
+			return callback(notHitKeys)
+		}, force)
+		if err == nil {
+			event.Misses = int(atomic.LoadInt64(&misses))
+			event.Hits = len(keys) - event.Misses
+		}
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) TTL(key string) (ttl time.Duration, err error) {
+	err = h.runKey("TTL", key, func(next Driver[V]) error {
+		ttl, err = next.TTL(key)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Touch(key string, ttl time.Duration) error {
+	return h.runKey("Touch", key, func(next Driver[V]) error {
+		return next.Touch(key, ttl)
+	})
+}
+
+func (h *hookedDriver[V]) TouchMany(keys []string, ttl time.Duration) error {
+	return h.runKeys("TouchMany", len(keys), func(next Driver[V]) error {
+		return next.TouchMany(keys, ttl)
+	})
+}
+
+func (h *hookedDriver[V]) ExpireAt(key string, at time.Time) error {
+	return h.runKey("ExpireAt", key, func(next Driver[V]) error {
+		return next.ExpireAt(key, at)
+	})
+}
+
+func (h *hookedDriver[V]) ExpireAtMany(keys []string, at time.Time) error {
+	return h.runKeys("ExpireAtMany", len(keys), func(next Driver[V]) error {
+		return next.ExpireAtMany(keys, at)
+	})
+}
+
+func (h *hookedDriver[V]) Persist(key string) error {
+	return h.runKey("Persist", key, func(next Driver[V]) error {
+		return next.Persist(key)
+	})
+}
+
+func (h *hookedDriver[V]) PersistMany(keys []string) error {
+	return h.runKeys("PersistMany", len(keys), func(next Driver[V]) error {
+		return next.PersistMany(keys)
+	})
+}
+
+func (h *hookedDriver[V]) GetAndTouch(key string, ttl time.Duration) (result V, err error) {
+	event := &HookEvent{Operation: "GetAndTouch", Key: key, Count: 1}
+	err = h.run(event, func(next Driver[V]) error {
+		result, err = next.GetAndTouch(key, ttl)
+		event.lookup(err)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Pull(key string) (result V, err error) {
+	event := &HookEvent{Operation: "Pull", Key: key, Count: 1}
+	err = h.run(event, func(next Driver[V]) error {
+		result, err = next.Pull(key)
+		event.lookup(err)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) GetSet(key string, value V, t time.Duration) (result V, err error) {
+	event := &HookEvent{Operation: "GetSet", Key: key, Count: 1}
+	err = h.run(event, func(next Driver[V]) error {
+		result, err = next.GetSet(key, value, t)
+		event.lookup(err)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) CompareAndSwap(key string, old, value V, t time.Duration) (swapped bool, err error) {
+	err = h.runKey("CompareAndSwap", key, func(next Driver[V]) error {
+		swapped, err = next.CompareAndSwap(key, old, value, t)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (result V, err error) {
+	err = h.runKey("Update", key, func(next Driver[V]) error {
+		result, err = next.Update(key, t, fn)
+		return err
+	})
+	return
+}
+
+func (h *hookedDriver[V]) Scan(pattern string, batchSize int, fn func(key string) error) error {
+	return h.runKeys("Scan", 0, func(next Driver[V]) error {
+		return next.Scan(pattern, batchSize, fn)
+	})
+}
+
+func (h *hookedDriver[V]) ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error {
+	return h.runKeys("ScanItems", 0, func(next Driver[V]) error {
+		return next.ScanItems(pattern, batchSize, fn)
+	})
+}
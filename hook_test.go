@@ -0,0 +1,219 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hookCtxKey struct{}
+
+// recordingHook records the events it sees after the operations
+type recordingHook struct {
+	name   string
+	mu     sync.Mutex
+	calls  *[]string
+	events []HookEvent
+}
+
+func (h *recordingHook) BeforeOperation(ctx context.Context, event *HookEvent) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, hookCtxKey{}, h.name)
+}
+
+func (h *recordingHook) AfterOperation(ctx context.Context, event *HookEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.calls = append(*h.calls, "after "+h.name+" "+ctx.Value(hookCtxKey{}).(string))
+	h.events = append(h.events, *event)
+}
+
+func (h *recordingHook) last() HookEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.events[len(h.events)-1]
+}
+
+func setupHookedDriver(t *testing.T, register func(driverName string, optionFns ...OptionFunc) error, optionFns ...OptionFunc) (Driver[string], *recordingHook, *[]string) {
+	t.Helper()
+	var calls []string
+	hook := &recordingHook{name: "first", calls: &calls}
+	driverName := nextDriverName("hook_test")
+	require.NoError(t, register(driverName, append([]OptionFunc{WithHooks(hook, &recordingHook{name: "second", calls: &calls})}, optionFns...)...))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	return driver, hook, &calls
+}
+
+func testHooks(t *testing.T, register func(driverName string, optionFns ...OptionFunc) error, driverType DriverType) {
+	driver, hook, calls := setupHookedDriver(t, register)
+
+	t.Run("order and context", func(t *testing.T) {
+		*calls = nil
+		assert.NoError(t, driver.Set("key", "value", time.Minute))
+		assert.Equal(t, []string{"before first", "before second", "after second second", "after first first"}, *calls)
+		event := hook.last()
+		assert.Equal(t, HookEvent{Operation: "Set", DriverName: event.DriverName, DriverType: driverType, Key: "key", Count: 1}, event)
+		assert.Contains(t, event.DriverName, "hook_test")
+	})
+	t.Run("hits and misses", func(t *testing.T) {
+		_, err := driver.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, hook.last().Hits)
+
+		_, err = driver.Get("missing")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Equal(t, 1, hook.last().Misses)
+		assert.NoError(t, hook.last().Err)
+
+		_, err = driver.Many([]string{"key", "missing"})
+		assert.NoError(t, err)
+		event := hook.last()
+		assert.Equal(t, "Many", event.Operation)
+		assert.Equal(t, []int{2, 1, 1}, []int{event.Count, event.Hits, event.Misses})
+
+		_, err = driver.Remember("loaded", time.Minute, func() (string, error) {
+			return "value", nil
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, hook.last().Misses)
+		_, err = driver.Remember("loaded", time.Minute, func() (string, error) {
+			return "value", nil
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, hook.last().Hits)
+
+		_, err = driver.RememberMany([]string{"key", "a", "b"}, time.Minute, func(notHitKeys []string) (map[string]string, error) {
+			return map[string]string{"a": "1", "b": "2"}, nil
+		}, false)
+		assert.NoError(t, err)
+		event = hook.last()
+		assert.Equal(t, []int{3, 1, 2}, []int{event.Count, event.Hits, event.Misses})
+	})
+	t.Run("errors", func(t *testing.T) {
+		sourceErr := errors.New("source down")
+		_, err := driver.Remember("failed", time.Minute, func() (string, error) {
+			return "", sourceErr
+		}, false)
+		assert.ErrorIs(t, err, sourceErr)
+		assert.ErrorIs(t, hook.last().Err, sourceErr)
+
+		assert.ErrorIs(t, driver.Add("key", "value", time.Minute), ErrCacheExisted)
+		assert.NoError(t, hook.last().Err)
+	})
+	t.Run("derived drivers keep the hooks", func(t *testing.T) {
+		assert.NoError(t, driver.WithCtx(context.Background()).Tags("tag").Set("tagged", "value", time.Minute))
+		assert.Equal(t, "Set", hook.last().Operation)
+		assert.NoError(t, driver.Tags("tag").Flush())
+		assert.Equal(t, "Flush", hook.last().Operation)
+		_, err := driver.Get("tagged")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+	assert.NoError(t, driver.Flush())
+}
+
+func TestGoCacheHooks(t *testing.T) {
+	testHooks(t, func(driverName string, optionFns ...OptionFunc) error {
+		return RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), "hook", optionFns...)
+	}, driverMemory)
+}
+
+func TestRedisHooks(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+	testHooks(t, func(driverName string, optionFns ...OptionFunc) error {
+		return RegisterRedisDriver(driverName, client, "hook", optionFns...)
+	}, driverRedis)
+}
+
+func TestHashedHookKeys(t *testing.T) {
+	driver, hook, _ := setupHookedDriver(t, func(driverName string, optionFns ...OptionFunc) error {
+		return RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), "hook", optionFns...)
+	}, WithHashedHookKeys())
+
+	assert.NoError(t, driver.Set("user:alice@example.com", "value", time.Minute))
+	key := hook.last().Key
+	assert.Len(t, key, 16)
+	assert.NotContains(t, key, "alice")
+	assert.Equal(t, hashHookKey("user:alice@example.com"), key)
+}
+
+// cancelingHook runs the operations with a canceled context
+type cancelingHook struct{}
+
+func (cancelingHook) BeforeOperation(ctx context.Context, event *HookEvent) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	return ctx
+}
+
+func (cancelingHook) AfterOperation(ctx context.Context, event *HookEvent) {}
+
+func TestHookContextReachesDriver(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	driverName := nextDriverName("hook_test")
+	require.NoError(t, RegisterRedisDriver(driverName, redis.NewClient(&redis.Options{Addr: mr.Addr()}), "", WithHooks(cancelingHook{})))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, driver.Set("key", "value", time.Minute), context.Canceled)
+	assert.False(t, mr.Exists("key"))
+}
+
+func TestHookSharedLoadsAreMisses(t *testing.T) {
+	var calls []string
+	hook := &recordingHook{name: "hook", calls: &calls}
+	driverName := nextDriverName("hook_test")
+	require.NoError(t, RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), "hook", WithHooks(hook)))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := driver.Remember("key", time.Minute, func() (string, error) {
+			close(started)
+			<-release
+			return "value", nil
+		}, false)
+		assert.NoError(t, err)
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		_, err := driver.RememberMany([]string{"key"}, time.Minute, func(notHitKeys []string) (map[string]string, error) {
+			return nil, errors.New("the load of the first caller is shared")
+		}, false)
+		assert.NoError(t, err)
+	}()
+	// let the second caller wait for the load of the first one
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	require.Len(t, hook.events, 2)
+	for _, event := range hook.events {
+		assert.Equal(t, []int{0, 1}, []int{event.Hits, event.Misses}, event.Operation)
+	}
+}
@@ -0,0 +1,217 @@
+package cacheit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+)
+
+// invalidationRetryInterval wait time before receiving again after a pub/sub error
+const invalidationRetryInterval = 100 * time.Millisecond
+
+// invalidation message published on the bus channel
+type invalidation struct {
+	// Origin bus id of the publisher, a bus ignores its own messages
+	Origin string `json:"origin"`
+	// Keys prefixed cache keys to evict
+	Keys []string `json:"keys,omitempty"`
+	// Flush evict every key of Prefix, or the whole cache if Prefix is empty
+	Flush  bool   `json:"flush,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// InvalidationBus broadcasts the mutations of drivers to other processes over redis pub/sub,
+// so that they can evict the stale copies of their local go-cache.
+type InvalidationBus struct {
+	client  redis.UniversalClient
+	channel string
+	origin  string
+	pubsub  *redis.PubSub
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu      sync.RWMutex
+	targets []busTarget
+}
+
+// busTarget the go-cache and the prefix of a driver attached to the bus
+type busTarget struct {
+	memCache *gocache.Cache
+	prefix   string
+}
+
+// NewInvalidationBus creates a bus publishing and subscribing on channel with the redis client of the
+// registered driver driverName, pass it to the drivers to keep in sync with WithInvalidationBus.
+// When the subscription is re-established after a connection loss, the prefixes of the attached drivers are
+// flushed from their go-cache because invalidations may have been missed in between.
+func NewInvalidationBus(driverName string, channel string) (*InvalidationBus, error) {
+	value, ok := registerDrivers.Load(driverName)
+	if !ok {
+		return nil, fmt.Errorf("cached driver: %s not registered", driverName)
+	}
+	client := value.(*baseDriver).redisClient
+	if client == nil {
+		return nil, fmt.Errorf("cached driver: %s has no redis client", driverName)
+	}
+	origin, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := client.Subscribe(ctx, channel)
+	// wait for the subscription to be confirmed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		_ = pubsub.Close()
+		return nil, err
+	}
+	bus := &InvalidationBus{
+		client:  client,
+		channel: channel,
+		origin:  origin,
+		pubsub:  pubsub,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go bus.receive(ctx)
+	return bus, nil
+}
+
+// Close stop receiving invalidations
+func (b *InvalidationBus) Close() error {
+	b.cancel()
+	err := b.pubsub.Close()
+	<-b.done
+	return err
+}
+
+// attach evict the keys of memCache on invalidations from other processes, and the keys of prefix on resubscribe
+func (b *InvalidationBus) attach(memCache *gocache.Cache, prefix string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	target := busTarget{memCache: memCache, prefix: prefix}
+	for _, t := range b.targets {
+		if t == target {
+			return
+		}
+	}
+	b.targets = append(b.targets, target)
+}
+
+func (b *InvalidationBus) publish(ctx context.Context, msg invalidation) error {
+	msg.Origin = b.origin
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+func (b *InvalidationBus) receive(ctx context.Context) {
+	defer close(b.done)
+	for {
+		msg, err := b.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// go-redis reconnects and subscribes again on the next receive
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(invalidationRetryInterval):
+			}
+			continue
+		}
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				// resubscribed after a connection loss, messages may have been missed
+				b.resync()
+			}
+		case *redis.Message:
+			var inv invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil || inv.Origin == b.origin {
+				continue
+			}
+			b.evict(inv)
+		}
+	}
+}
+
+// evict apply an invalidation to the attached go-cache instances
+func (b *InvalidationBus) evict(inv invalidation) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, memCache := range b.caches() {
+		if inv.Flush {
+			flushMemCache(memCache, inv.Prefix)
+			continue
+		}
+		for _, key := range inv.Keys {
+			memCache.Delete(key)
+		}
+	}
+}
+
+// caches the distinct go-cache instances of the attached drivers, b.mu must be held
+func (b *InvalidationBus) caches() []*gocache.Cache {
+	caches := make([]*gocache.Cache, 0, len(b.targets))
+	for _, t := range b.targets {
+		if !lo.Contains(caches, t.memCache) {
+			caches = append(caches, t.memCache)
+		}
+	}
+	return caches
+}
+
+// resync flush the prefix of every attached driver from its go-cache, the other drivers sharing the
+// go-cache keep their keys
+func (b *InvalidationBus) resync() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, t := range b.targets {
+		flushMemCache(t.memCache, t.prefix)
+	}
+}
+
+// flushMemCache remove every key of prefix from memCache, or all keys if prefix is empty
+func flushMemCache(memCache *gocache.Cache, prefix string) {
+	if prefix == "" {
+		memCache.Flush()
+		return
+	}
+	prefix += ":"
+	for key := range memCache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			memCache.Delete(key)
+		}
+	}
+}
+
+// publishInvalidation publish the eviction of keys to other processes
+func (d *baseDriver) publishInvalidation(keys ...string) error {
+	if d.bus == nil || len(keys) == 0 {
+		return nil
+	}
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return err
+	}
+	return d.bus.publish(d.ctx, invalidation{Keys: cacheKeys})
+}
+
+// publishFlush publish the flush of the driver prefix to other processes
+func (d *baseDriver) publishFlush() error {
+	if d.bus == nil {
+		return nil
+	}
+	return d.bus.publish(d.ctx, invalidation{Flush: true, Prefix: d.prefix})
+}
@@ -0,0 +1,192 @@
+package cacheit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupInvalidationBus(t *testing.T, mr *miniredis.Miniredis) *InvalidationBus {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close(), "close redis client")
+	})
+
+	driverName := nextDriverName("bus_redis")
+	require.NoError(t, RegisterRedisDriver(driverName, client, ""))
+	bus, err := NewInvalidationBus(driverName, "cacheit:invalidation")
+	require.NoError(t, err, "create invalidation bus")
+	t.Cleanup(func() {
+		_ = bus.Close()
+	})
+	return bus
+}
+
+func setupBusGoCacheDriver(t *testing.T, bus *InvalidationBus, prefix string) (*GoCacheDriver[string], *gocache.Cache) {
+	t.Helper()
+
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	driverName := nextDriverName("bus_mem")
+	require.NoError(t, RegisterGoCacheDriver(driverName, memCache, prefix, WithInvalidationBus(bus)))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	return driver.(*GoCacheDriver[string]), memCache
+}
+
+func TestNewInvalidationBusRequiresRedisDriver(t *testing.T) {
+	_, err := NewInvalidationBus("non_existing_driver", "channel")
+	assert.Error(t, err)
+
+	driverName := nextDriverName("bus_no_redis")
+	require.NoError(t, RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), ""))
+	_, err = NewInvalidationBus(driverName, "channel")
+	assert.Error(t, err)
+}
+
+func TestInvalidationBusEvictsOtherProcesses(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	driverA, memA := setupBusGoCacheDriver(t, setupInvalidationBus(t, mr), "prefix")
+	_, memB := setupBusGoCacheDriver(t, setupInvalidationBus(t, mr), "prefix")
+
+	evicted := func(key string) func() bool {
+		return func() bool {
+			_, found := memB.Get(key)
+			return !found
+		}
+	}
+
+	// seed the local copies of B directly, its own writes would be published to A as well
+	memB.Set("prefix:forget", "b", time.Minute)
+	assert.NoError(t, driverA.Forget("forget"))
+	assert.Eventually(t, evicted("prefix:forget"), time.Second, 10*time.Millisecond)
+
+	memB.Set("prefix:set", "b", time.Minute)
+	assert.NoError(t, driverA.Set("set", "a", time.Minute))
+	assert.Eventually(t, evicted("prefix:set"), time.Second, 10*time.Millisecond)
+	got, err := driverA.Get("set")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", got)
+
+	memB.Set("prefix:m1", "b", time.Minute)
+	memB.Set("prefix:m2", "b", time.Minute)
+	assert.NoError(t, driverA.DelMany([]string{"m1", "m2"}))
+	assert.Eventually(t, evicted("prefix:m1"), time.Second, 10*time.Millisecond)
+	assert.Eventually(t, evicted("prefix:m2"), time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, driverA.Set("expire", "a", time.Minute))
+	assert.Eventually(t, evicted("prefix:expire"), time.Second, 10*time.Millisecond)
+	memB.Set("prefix:expire", "b", time.Minute)
+	assert.NoError(t, driverA.ExpireAt("expire", time.Now().Add(-time.Second)))
+	assert.Eventually(t, evicted("prefix:expire"), time.Second, 10*time.Millisecond)
+	memB.Set("prefix:touch", "b", time.Minute)
+	assert.ErrorIs(t, driverA.Touch("touch", time.Minute), ErrCacheMiss)
+	assert.ErrorIs(t, driverA.Touch("expire", time.Minute), ErrCacheMiss)
+	_, found := memB.Get("prefix:touch")
+	assert.True(t, found, "the missing keys are not published")
+
+	memB.Set("prefix:flush", "b", time.Minute)
+	memB.Set("other:flush", "raw", time.Minute)
+	assert.NoError(t, driverA.Flush())
+	assert.Eventually(t, evicted("prefix:flush"), time.Second, 10*time.Millisecond)
+	_, found = memB.Get("other:flush")
+	assert.True(t, found)
+
+	_, found = memA.Get("prefix:set")
+	assert.False(t, found)
+}
+
+func TestInvalidationBusFlushesLocalOnResubscribe(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	driver, memCache := setupBusGoCacheDriver(t, setupInvalidationBus(t, mr), "prefix")
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	otherName := nextDriverName("bus_mem")
+	require.NoError(t, RegisterGoCacheDriver(otherName, memCache, "other"))
+	other, err := Use[string](otherName)
+	require.NoError(t, err)
+	assert.NoError(t, other.Set("key", "value", time.Minute))
+
+	mr.Close()
+	require.NoError(t, mr.Restart())
+
+	assert.Eventually(t, func() bool {
+		_, found := memCache.Get("prefix:key")
+		return !found
+	}, 5*time.Second, 10*time.Millisecond)
+	_, found := memCache.Get("other:key")
+	assert.True(t, found, "the drivers not attached to the bus keep their keys")
+}
+
+func TestInvalidationBusWithTieredDriver(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	var drivers []Driver[string]
+	var memCaches []*gocache.Cache
+	for i := 0; i < 2; i++ {
+		memCache := gocache.New(5*time.Minute, 10*time.Minute)
+		driverName := nextDriverName("bus_tiered")
+		require.NoError(t, RegisterTieredDriver(driverName, memCache, client, "prefix", time.Minute, WithInvalidationBus(setupInvalidationBus(t, mr))))
+		driver, err := Use[string](driverName)
+		require.NoError(t, err)
+		drivers = append(drivers, driver)
+		memCaches = append(memCaches, memCache)
+	}
+
+	assert.NoError(t, drivers[0].Set("key", "v1", time.Minute))
+	got, err := drivers[1].Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", got)
+
+	assert.NoError(t, drivers[0].Set("key", "v2", time.Minute))
+	assert.Eventually(t, func() bool {
+		_, found := memCaches[1].Get("prefix:key")
+		return !found
+	}, time.Second, 10*time.Millisecond)
+
+	got, err = drivers[1].Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", got)
+}
+
+func TestInvalidationBusPublishesFailedChunks(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+	driverName := nextDriverName("bus_redis")
+	require.NoError(t, RegisterRedisDriver(driverName, client, "prefix", WithChunking(ChunkPolicy{Size: 2}), WithInvalidationBus(setupInvalidationBus(t, mr))))
+	driver, err := Use[int](driverName)
+	require.NoError(t, err)
+	client.AddHook(&chunkHook{failKey: "prefix:b"})
+	_, memB := setupBusGoCacheDriver(t, setupInvalidationBus(t, mr), "prefix")
+
+	memB.Set("prefix:b", "stale", time.Minute)
+	err = driver.SetMany([]Many[int]{{Key: "a", Value: 1}, {Key: "b", Value: 2}, {Key: "c", Value: 3}})
+	require.ErrorAs(t, err, new(*ChunkError))
+	assert.Eventually(t, func() bool {
+		_, found := memB.Get("prefix:b")
+		return !found
+	}, time.Second, 10*time.Millisecond, "the keys of the failed chunks may be partially written")
+}
@@ -0,0 +1,49 @@
+package cacheit
+
+import (
+	"math/rand"
+	"time"
+)
+
+// JitterPolicy bounded random jitter added to the positive ttls written by a driver, so the items written
+// together with the same ttl do not expire together.
+type JitterPolicy struct {
+	// Max absolute upper bound of the jitter, the cap of the Ratio jitter if both are set
+	Max time.Duration
+	// Ratio upper bound of the jitter as a fraction of the ttl, e.g. 0.1 for up to 10% of the ttl
+	Ratio float64
+	// Rand random source returning numbers in [0, 1), it must be safe for concurrent use, math/rand.Float64 by default
+	Rand func() float64
+}
+
+// bound the upper bound of the jitter of ttl
+func (p *JitterPolicy) bound(ttl time.Duration) time.Duration {
+	if p.Ratio <= 0 {
+		return p.Max
+	}
+	bound := time.Duration(float64(ttl) * p.Ratio)
+	if p.Max > 0 && bound > p.Max {
+		return p.Max
+	}
+	return bound
+}
+
+// jitter the ttl the driver writes for ttl, ttls without expiration are left untouched
+func (d *baseDriver) jitter(ttl time.Duration) time.Duration {
+	if d.jitterPolicy == nil || ttl <= 0 {
+		return ttl
+	}
+	random := d.jitterPolicy.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+	return ttl + time.Duration(random()*float64(d.jitterPolicy.bound(ttl)))
+}
+
+// maxJitter the longest ttl the driver may write for ttl
+func (d *baseDriver) maxJitter(ttl time.Duration) time.Duration {
+	if d.jitterPolicy == nil || ttl <= 0 {
+		return ttl
+	}
+	return ttl + d.jitterPolicy.bound(ttl)
+}
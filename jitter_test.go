@@ -0,0 +1,97 @@
+package cacheit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterPolicyBound(t *testing.T) {
+	assert.Equal(t, 10*time.Second, (&JitterPolicy{Max: 10 * time.Second}).bound(time.Hour))
+	assert.Equal(t, 6*time.Minute, (&JitterPolicy{Ratio: 0.1}).bound(time.Hour))
+	assert.Equal(t, time.Minute, (&JitterPolicy{Ratio: 0.1, Max: time.Minute}).bound(time.Hour))
+	assert.Equal(t, 30*time.Second, (&JitterPolicy{Ratio: 0.1, Max: time.Minute}).bound(5*time.Minute))
+}
+
+func TestJitter(t *testing.T) {
+	d := &baseDriver{}
+	assert.Equal(t, time.Minute, d.jitter(time.Minute), "no policy")
+
+	d.jitterPolicy = &JitterPolicy{Ratio: 0.5, Rand: func() float64 { return 0.5 }}
+	assert.Equal(t, 75*time.Second, d.jitter(time.Minute))
+	assert.Equal(t, 90*time.Second, d.maxJitter(time.Minute))
+	assert.Equal(t, time.Duration(0), d.jitter(0))
+	assert.Equal(t, NoExpirationTTL, d.jitter(NoExpirationTTL))
+
+	d.jitterPolicy = &JitterPolicy{Max: time.Second}
+	for i := 0; i < 100; i++ {
+		ttl := d.jitter(time.Minute)
+		assert.GreaterOrEqual(t, ttl, time.Minute)
+		assert.Less(t, ttl, time.Minute+time.Second)
+	}
+}
+
+func TestWithTTLJitterRejectsNegativeBounds(t *testing.T) {
+	assert.Error(t, WithTTLJitter(JitterPolicy{Max: -time.Second})(&baseDriver{}))
+	assert.Error(t, WithTTLJitter(JitterPolicy{Ratio: -0.1})(&baseDriver{}))
+}
+
+// halfJitter a deterministic jitter of 30s for a ttl of one minute
+var halfJitter = WithTTLJitter(JitterPolicy{Ratio: 1, Rand: func() float64 { return 0.5 }})
+
+func testTTLJitter(t *testing.T, driver Driver[int]) {
+	assertJittered := func(key string) {
+		t.Helper()
+		ttl, err := driver.TTL(key)
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, 89*time.Second, key)
+		assert.LessOrEqual(t, ttl, 90*time.Second, key)
+	}
+	assert.NoError(t, driver.Set("set", 1, time.Minute))
+	assertJittered("set")
+	assert.NoError(t, driver.Add("add", 1, time.Minute))
+	assertJittered("add")
+	assert.NoError(t, driver.SetMany([]Many[int]{{Key: "many", Value: 1, TTL: time.Minute}}))
+	assertJittered("many")
+	assert.NoError(t, driver.SetNumber("number", 1, time.Minute))
+	assertJittered("number")
+	_, err := driver.Remember("remember", time.Minute, func() (int, error) {
+		return 1, nil
+	}, false)
+	assert.NoError(t, err)
+	assertJittered("remember")
+	_, err = driver.RememberMany([]string{"remember_many"}, time.Minute, func(keys []string) (map[string]int, error) {
+		return map[string]int{"remember_many": 1}, nil
+	}, false)
+	assert.NoError(t, err)
+	assertJittered("remember_many")
+	_, err = driver.RememberStale("stale", 30*time.Second, 30*time.Second, func() (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assertJittered("stale")
+
+	assert.NoError(t, driver.Forever("forever", 1))
+	ttl, err := driver.TTL("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, NoExpirationTTL, ttl)
+}
+
+func TestRedisTTLJitter(t *testing.T) {
+	testTTLJitter(t, setupRedisDriverWithPrefix[int](t, "cache_prefix", halfJitter))
+}
+
+func TestGoCacheTTLJitter(t *testing.T) {
+	testTTLJitter(t, setupGoCacheDriverWithPrefix[int](t, "cache_prefix", halfJitter))
+}
+
+func TestTieredTTLJitter(t *testing.T) {
+	testTTLJitter(t, setupTieredDriver[int](t, time.Minute, halfJitter))
+}
+
+func TestTaggedIndexCoversJitter(t *testing.T) {
+	driver := setupGoCacheDriverWithPrefix[int](t, "cache_prefix", halfJitter)
+	assert.NoError(t, driver.Tags("tag").Set("key", 1, time.Minute))
+	assert.Greater(t, driver.memTagSet("tag").members["key"], time.Now().Add(89*time.Second).UnixMilli())
+}
@@ -0,0 +1,235 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// lockRetryInterval wait time between two attempts of Block
+const lockRetryInterval = 100 * time.Millisecond
+
+var (
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	ErrLockNotOwner    = errors.New("lock not owned by the current owner")
+)
+
+var (
+	// releaseLockScript delete the lock only if it is held by the owner
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	// refreshLockScript reset the lock ttl only if it is held by the owner
+	refreshLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	if tonumber(ARGV[2]) > 0 then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	return redis.call("PERSIST", KEYS[1]) + 1
+end
+return 0`)
+)
+
+// memLockMu serializes the memory locks operations, go-cache has no compare-and-delete
+var memLockMu sync.Mutex
+
+// Lock atomic lock interface
+type Lock interface {
+	// Acquire Attempt to acquire the lock once, returns ErrLockNotAcquired if it is held by another owner.
+	Acquire() error
+	// Block Attempt to acquire the lock for the given wait duration, returns ErrLockNotAcquired on timeout.
+	Block(ctx context.Context, wait time.Duration) error
+	// Release Release the lock, returns ErrLockNotOwner if it is not held by the current owner.
+	Release() error
+	// ForceRelease Release the lock regardless of its owner.
+	ForceRelease() error
+	// Refresh Reset the lock ttl, returns ErrLockNotOwner if it is not held by the current owner.
+	Refresh(ttl time.Duration) error
+	// Owner Get the owner token of the lock.
+	Owner() string
+}
+
+// NewLock creates a lock named name on the registered driver driverName, held for ttl once acquired.
+// A ttl of 0 holds the lock until it is released.
+func NewLock(driverName string, name string, ttl time.Duration) (Lock, error) {
+	owner, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	return RestoreLock(driverName, name, owner, ttl)
+}
+
+// RestoreLock creates a lock instance of an existing owner, e.g. to release it from another process.
+func RestoreLock(driverName string, name string, owner string, ttl time.Duration) (Lock, error) {
+	value, ok := registerDrivers.Load(driverName)
+	if !ok {
+		return nil, fmt.Errorf("cached driver: %s not registered", driverName)
+	}
+	d := value.(*baseDriver)
+	l := baseLock{
+		driver: d,
+		key:    d.lockKey(name), // locks outlive Flush and the generations of a versioned namespace
+		owner:  owner,
+		ttl:    ttl,
+	}
+	switch d.driverType {
+	case driverRedis, driverTiered:
+		return &redisLock{l}, nil
+	case driverMemory:
+		return &memoryLock{l}, nil
+	default:
+		return nil, fmt.Errorf("unsupport driver type: %s", d.driverType)
+	}
+}
+
+type baseLock struct {
+	driver *baseDriver
+	key    string
+	owner  string
+	ttl    time.Duration
+}
+
+func (l *baseLock) Owner() string {
+	return l.owner
+}
+
+// block retry acquire until it succeeds, wait elapses or ctx is done
+func (l *baseLock) block(ctx context.Context, wait time.Duration, acquire func() error) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+	for {
+		err := acquire()
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return ErrLockNotAcquired
+		case <-ticker.C:
+		}
+	}
+}
+
+// redisLock lock implemented with redis SET NX
+type redisLock struct {
+	baseLock
+}
+
+func (l *redisLock) Acquire() error {
+	return l.acquire(l.driver.ctx)
+}
+
+func (l *redisLock) acquire(ctx context.Context) error {
+	ok, err := l.driver.redisClient.SetNX(ctx, l.key, l.owner, normalizeTTL(l.ttl)).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotAcquired
+	}
+	return nil
+}
+
+func (l *redisLock) Block(ctx context.Context, wait time.Duration) error {
+	return l.block(ctx, wait, func() error {
+		return l.acquire(ctx)
+	})
+}
+
+func (l *redisLock) Release() error {
+	res, err := releaseLockScript.Run(l.driver.ctx, l.driver.redisClient, []string{l.key}, l.owner).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrLockNotOwner
+	}
+	return nil
+}
+
+func (l *redisLock) ForceRelease() error {
+	return l.driver.redisClient.Del(l.driver.ctx, l.key).Err()
+}
+
+func (l *redisLock) Refresh(ttl time.Duration) error {
+	res, err := refreshLockScript.Run(l.driver.ctx, l.driver.redisClient, []string{l.key}, l.owner, normalizeTTL(ttl).Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrLockNotOwner
+	}
+	l.ttl = ttl
+	return nil
+}
+
+// memoryLock in-process lock implemented with go-cache Add
+type memoryLock struct {
+	baseLock
+}
+
+// expiration go-cache expiration of the lock
+func (l *memoryLock) expiration(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return gocache.NoExpiration
+	}
+	return ttl
+}
+
+// held report whether the lock is held by the owner, memLockMu must be held
+func (l *memoryLock) held() bool {
+	owner, found := l.driver.memState().Get(l.key)
+	return found && owner == l.owner
+}
+
+func (l *memoryLock) Acquire() error {
+	memLockMu.Lock()
+	defer memLockMu.Unlock()
+	if err := l.driver.memState().Add(l.key, l.owner, l.expiration(l.ttl)); err != nil {
+		return ErrLockNotAcquired
+	}
+	return nil
+}
+
+func (l *memoryLock) Block(ctx context.Context, wait time.Duration) error {
+	return l.block(ctx, wait, l.Acquire)
+}
+
+func (l *memoryLock) Release() error {
+	memLockMu.Lock()
+	defer memLockMu.Unlock()
+	if !l.held() {
+		return ErrLockNotOwner
+	}
+	l.driver.memState().Delete(l.key)
+	return nil
+}
+
+func (l *memoryLock) ForceRelease() error {
+	memLockMu.Lock()
+	defer memLockMu.Unlock()
+	l.driver.memState().Delete(l.key)
+	return nil
+}
+
+func (l *memoryLock) Refresh(ttl time.Duration) error {
+	memLockMu.Lock()
+	defer memLockMu.Unlock()
+	if !l.held() {
+		return ErrLockNotOwner
+	}
+	l.driver.memState().Set(l.key, l.owner, l.expiration(ttl))
+	l.ttl = ttl
+	return nil
+}
@@ -0,0 +1,176 @@
+package cacheit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLock(t *testing.T, driverName string) {
+	t.Run("acquire and release", func(t *testing.T) {
+		lock, err := NewLock(driverName, "acquire", time.Minute)
+		require.NoError(t, err)
+		other, err := NewLock(driverName, "acquire", time.Minute)
+		require.NoError(t, err)
+		assert.NotEqual(t, lock.Owner(), other.Owner())
+
+		assert.NoError(t, lock.Acquire())
+		assert.ErrorIs(t, other.Acquire(), ErrLockNotAcquired)
+		assert.ErrorIs(t, other.Release(), ErrLockNotOwner)
+		assert.ErrorIs(t, other.Refresh(time.Minute), ErrLockNotOwner)
+
+		assert.NoError(t, lock.Refresh(2*time.Minute))
+		assert.NoError(t, lock.Release())
+		assert.ErrorIs(t, lock.Release(), ErrLockNotOwner)
+
+		assert.NoError(t, other.Acquire())
+		assert.NoError(t, lock.ForceRelease())
+		assert.NoError(t, lock.Acquire())
+		assert.NoError(t, lock.Release())
+	})
+	t.Run("restore lock", func(t *testing.T) {
+		lock, err := NewLock(driverName, "restore", time.Minute)
+		require.NoError(t, err)
+		assert.NoError(t, lock.Acquire())
+
+		restored, err := RestoreLock(driverName, "restore", lock.Owner(), time.Minute)
+		require.NoError(t, err)
+		assert.NoError(t, restored.Release())
+		assert.ErrorIs(t, lock.Release(), ErrLockNotOwner)
+	})
+	t.Run("block", func(t *testing.T) {
+		lock, err := NewLock(driverName, "block", time.Minute)
+		require.NoError(t, err)
+		other, err := NewLock(driverName, "block", time.Minute)
+		require.NoError(t, err)
+		assert.NoError(t, lock.Acquire())
+
+		assert.ErrorIs(t, other.Block(context.Background(), 150*time.Millisecond), ErrLockNotAcquired)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, other.Block(ctx, time.Second), context.Canceled)
+
+		time.AfterFunc(150*time.Millisecond, func() {
+			_ = lock.Release()
+		})
+		assert.NoError(t, other.Block(context.Background(), 2*time.Second))
+		assert.NoError(t, other.Release())
+	})
+}
+
+func TestRedisLock(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+	driverName := nextDriverName("redis_lock")
+	require.NoError(t, RegisterRedisDriver(driverName, client, "lock_prefix"))
+
+	testLock(t, driverName)
+
+	lockKey := "\x00cacheit:lock:lock_prefix:ttl"
+	lock, err := NewLock(driverName, "ttl", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Acquire())
+	owner, err := client.Get(context.Background(), lockKey).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, lock.Owner(), owner)
+	assert.Equal(t, time.Minute, mr.TTL(lockKey))
+
+	assert.NoError(t, lock.Refresh(0))
+	assert.Equal(t, time.Duration(0), mr.TTL(lockKey))
+
+	mr.FastForward(2 * time.Minute)
+	assert.True(t, mr.Exists(lockKey))
+	assert.NoError(t, lock.Refresh(time.Second))
+	mr.FastForward(2 * time.Second)
+	assert.NoError(t, lock.Acquire())
+}
+
+func TestMemoryLock(t *testing.T) {
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	driverName := nextDriverName("mem_lock")
+	require.NoError(t, RegisterGoCacheDriver(driverName, memCache, "lock_prefix"))
+
+	testLock(t, driverName)
+
+	lockKey := "\x00cacheit:lock:lock_prefix:ttl"
+	lock, err := NewLock(driverName, "ttl", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Acquire())
+	_, found := memCache.Get(lockKey)
+	assert.False(t, found, "the lock is kept out of the go-cache")
+	owner, found := memStates.Load(memCache)
+	require.True(t, found)
+	owner, found = owner.(*gocache.Cache).Get(lockKey)
+	assert.True(t, found)
+	assert.Equal(t, lock.Owner(), owner)
+
+	time.Sleep(100 * time.Millisecond)
+	other, err := NewLock(driverName, "ttl", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, other.Acquire())
+	assert.ErrorIs(t, lock.Release(), ErrLockNotOwner)
+}
+
+func TestLockSurvivesFlush(t *testing.T) {
+	redisDriver := setupRedisDriverWithPrefix[string](t, "lock_prefix")
+	memDriver := setupGoCacheDriverWithPrefix[string](t, "lock_prefix")
+	for name, driver := range map[string]Driver[string]{redisDriver.name: redisDriver, memDriver.name: memDriver} {
+		t.Run(name, func(t *testing.T) {
+			lock, err := NewLock(name, "flush", time.Minute)
+			require.NoError(t, err)
+			assert.NoError(t, lock.Acquire())
+			assert.NoError(t, driver.Set("flush", "value", time.Minute))
+
+			assert.NoError(t, driver.Flush())
+			_, err = driver.Get("flush")
+			assert.ErrorIs(t, err, ErrCacheMiss)
+			assert.NoError(t, lock.Release())
+		})
+	}
+}
+
+func TestMemoryLockSurvivesFullFlush(t *testing.T) {
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	driverName := nextDriverName("mem_lock")
+	require.NoError(t, RegisterGoCacheDriver(driverName, memCache, ""))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	lock, err := NewLock(driverName, "flush", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Acquire())
+
+	assert.NoError(t, driver.Flush())
+	other, err := NewLock(driverName, "flush", time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, other.Acquire(), ErrLockNotAcquired)
+	assert.NoError(t, lock.Release())
+}
+
+func TestRedisLockBlockUsesContext(t *testing.T) {
+	driver := setupRedisDriverWithPrefix[string](t, "lock_prefix")
+	lock, err := NewLock(driver.name, "ctx", time.Minute)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, lock.Block(ctx, time.Second), context.Canceled)
+	assert.NoError(t, lock.Acquire())
+}
+
+func TestNewLockUnknownDriver(t *testing.T) {
+	_, err := NewLock("non_existing_driver", "name", time.Minute)
+	assert.Error(t, err)
+}
@@ -0,0 +1,598 @@
+package cacheit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Operation generic descriptor of a Driver method call seen by the middlewares
+type Operation struct {
+	// Name name of the Driver method, e.g. Get, SetMany, Remember
+	Name string
+	// Ctx context of the driver, middlewares may replace it to run the operation with another context
+	Ctx context.Context
+	// Keys keys of the operation, middlewares may rewrite them before calling next but must keep their number,
+	// the items of Many and RememberMany are still reported under the caller's keys
+	Keys []string
+	// Values values written by the operation aligned with Keys, each of the value type of the driver,
+	// middlewares may rewrite them but must keep their number, CompareAndSwap reports the new value only
+	Values []any
+	// TTL ttl of the written or touched items, SetMany items keep their own ttl, ExpireAt* report the ttl left
+	// until the expiration time and ignore changes to it
+	TTL time.Duration
+	// Result result of the operation once next returned, e.g. V for Get, map[string]V for Many
+	Result any
+}
+
+// Handler executes an operation
+type Handler func(op *Operation) error
+
+// Middleware intercepts the operations of a driver, it calls next to execute the operation, possibly
+// several times, or returns without calling it.
+type Middleware func(op *Operation, next Handler) error
+
+// chain the middlewares around handler, the first middleware is the outermost
+func chain(middlewares []Middleware, handler Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, next := middlewares[i], handler
+		handler = func(op *Operation) error {
+			return middleware(op, next)
+		}
+	}
+	return handler
+}
+
+// DriverWrapper forwards every method to Next, embed it to override some methods of a driver.
+// Tags, WithCtx and WithSerializer wrap the derived driver with Rewrap, or return it as is if Rewrap is nil.
+type DriverWrapper[V any] struct {
+	Next   Driver[V]
+	Rewrap func(next Driver[V]) Driver[V]
+}
+
+func (w *DriverWrapper[V]) base() *baseDriver {
+	return w.Next.(baseProvider).base()
+}
+
+// rewrap wrap a driver derived from Next
+func (w *DriverWrapper[V]) rewrap(next Driver[V]) Driver[V] {
+	if w.Rewrap == nil {
+		return next
+	}
+	return w.Rewrap(next)
+}
+
+func (w *DriverWrapper[V]) Add(key string, value V, t time.Duration) error {
+	return w.Next.Add(key, value, t)
+}
+
+func (w *DriverWrapper[V]) Set(key string, value V, t time.Duration) error {
+	return w.Next.Set(key, value, t)
+}
+
+func (w *DriverWrapper[V]) SetMany(many []Many[V]) error {
+	return w.Next.SetMany(many)
+}
+
+func (w *DriverWrapper[V]) Forever(key string, value V) error {
+	return w.Next.Forever(key, value)
+}
+
+func (w *DriverWrapper[V]) Forget(key string) error {
+	return w.Next.Forget(key)
+}
+
+func (w *DriverWrapper[V]) Del(key string) error {
+	return w.Next.Del(key)
+}
+
+func (w *DriverWrapper[V]) Flush() error {
+	return w.Next.Flush()
+}
+
+func (w *DriverWrapper[V]) Get(key string) (V, error) {
+	return w.Next.Get(key)
+}
+
+func (w *DriverWrapper[V]) Has(key string) (bool, error) {
+	return w.Next.Has(key)
+}
+
+func (w *DriverWrapper[V]) Many(keys []string) (map[string]V, error) {
+	return w.Next.Many(keys)
+}
+
+func (w *DriverWrapper[V]) DelMany(keys []string) error {
+	return w.Next.DelMany(keys)
+}
+
+func (w *DriverWrapper[V]) ForgetMany(keys []string) error {
+	return w.Next.ForgetMany(keys)
+}
+
+func (w *DriverWrapper[V]) SetNumber(key string, value V, t time.Duration) error {
+	return w.Next.SetNumber(key, value, t)
+}
+
+func (w *DriverWrapper[V]) Increment(key string, n V) (V, error) {
+	return w.Next.Increment(key, n)
+}
+
+func (w *DriverWrapper[V]) Decrement(key string, n V) (V, error) {
+	return w.Next.Decrement(key, n)
+}
+
+func (w *DriverWrapper[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (V, error) {
+	return w.Next.Remember(key, ttl, callback, force)
+}
+
+func (w *DriverWrapper[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
+	return w.Next.RememberForever(key, callback, force)
+}
+
+func (w *DriverWrapper[V]) RememberMany(keys []string, ttl time.Duration, callback func(notHitKeys []string) (map[string]V, error), force bool) (map[string]V, error) {
+	return w.Next.RememberMany(keys, ttl, callback, force)
+}
+
+func (w *DriverWrapper[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return w.Next.RememberStale(key, freshTTL, staleTTL, callback)
+}
+
+func (w *DriverWrapper[V]) TTL(key string) (time.Duration, error) {
+	return w.Next.TTL(key)
+}
+
+func (w *DriverWrapper[V]) Touch(key string, ttl time.Duration) error {
+	return w.Next.Touch(key, ttl)
+}
+
+func (w *DriverWrapper[V]) TouchMany(keys []string, ttl time.Duration) error {
+	return w.Next.TouchMany(keys, ttl)
+}
+
+func (w *DriverWrapper[V]) ExpireAt(key string, at time.Time) error {
+	return w.Next.ExpireAt(key, at)
+}
+
+func (w *DriverWrapper[V]) ExpireAtMany(keys []string, at time.Time) error {
+	return w.Next.ExpireAtMany(keys, at)
+}
+
+func (w *DriverWrapper[V]) Persist(key string) error {
+	return w.Next.Persist(key)
+}
+
+func (w *DriverWrapper[V]) PersistMany(keys []string) error {
+	return w.Next.PersistMany(keys)
+}
+
+func (w *DriverWrapper[V]) GetAndTouch(key string, ttl time.Duration) (V, error) {
+	return w.Next.GetAndTouch(key, ttl)
+}
+
+func (w *DriverWrapper[V]) Pull(key string) (V, error) {
+	return w.Next.Pull(key)
+}
+
+func (w *DriverWrapper[V]) GetSet(key string, value V, t time.Duration) (V, error) {
+	return w.Next.GetSet(key, value, t)
+}
+
+func (w *DriverWrapper[V]) CompareAndSwap(key string, old, value V, t time.Duration) (bool, error) {
+	return w.Next.CompareAndSwap(key, old, value, t)
+}
+
+func (w *DriverWrapper[V]) Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (V, error) {
+	return w.Next.Update(key, t, fn)
+}
+
+func (w *DriverWrapper[V]) Scan(pattern string, batchSize int, fn func(key string) error) error {
+	return w.Next.Scan(pattern, batchSize, fn)
+}
+
+func (w *DriverWrapper[V]) ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error {
+	return w.Next.ScanItems(pattern, batchSize, fn)
+}
+
+func (w *DriverWrapper[V]) Tags(tags ...string) Driver[V] {
+	return w.rewrap(w.Next.Tags(tags...))
+}
+
+func (w *DriverWrapper[V]) WithCtx(ctx context.Context) Driver[V] {
+	return w.rewrap(w.Next.WithCtx(ctx))
+}
+
+func (w *DriverWrapper[V]) WithSerializer(serializer Serializer) Driver[V] {
+	return w.rewrap(w.Next.WithSerializer(serializer))
+}
+
+// middlewareDriver a driver whose operations go through the middlewares of the registration
+type middlewareDriver[V any] struct {
+	DriverWrapper[V]
+}
+
+func newMiddlewareDriver[V any](driver Driver[V]) Driver[V] {
+	return &middlewareDriver[V]{DriverWrapper[V]{Next: driver, Rewrap: newMiddlewareDriver[V]}}
+}
+
+// run op through the middlewares, handler executes it on the wrapped driver
+func (m *middlewareDriver[V]) run(op *Operation, handler Handler) error {
+	d := m.base()
+	op.Ctx = d.ctx
+	return chain(d.middlewares, handler)(op)
+}
+
+// next the wrapped driver, bound to the context of op if a middleware replaced it
+func (m *middlewareDriver[V]) next(op *Operation) Driver[V] {
+	if op.Ctx == nil || op.Ctx == m.base().ctx {
+		return m.Next
+	}
+	return m.Next.WithCtx(op.Ctx)
+}
+
+// cloneKeys copy the keys of an operation, so that the middlewares rewriting them leave the caller's slice alone
+func cloneKeys(keys []string) []string {
+	return append([]string(nil), keys...)
+}
+
+// alignKeys check that the middlewares kept one key for each of the n keys of the caller
+func alignKeys(op *Operation, n int) error {
+	if len(op.Keys) != n {
+		return fmt.Errorf("operation %s keys mismatch: expected %d keys, got %d", op.Name, n, len(op.Keys))
+	}
+	return nil
+}
+
+// originItems the items keyed by the rewritten keys of op keyed by the caller's keys instead
+func originItems[V any](keys []string, op *Operation, items map[string]V) map[string]V {
+	if items == nil {
+		return nil
+	}
+	results := make(map[string]V, len(items))
+	for i, key := range op.Keys {
+		if item, ok := items[key]; ok {
+			results[keys[i]] = item
+		}
+	}
+	return results
+}
+
+// alignValues check that the middlewares kept the n values of the caller
+func alignValues(op *Operation, n int) error {
+	if len(op.Values) != n {
+		return fmt.Errorf("operation %s values mismatch: expected %d values, got %d", op.Name, n, len(op.Values))
+	}
+	return nil
+}
+
+// single run a single key operation, the middlewares must keep its key and its values
+func (m *middlewareDriver[V]) single(op *Operation, handler Handler) error {
+	values := len(op.Values)
+	return m.run(op, func(op *Operation) error {
+		if err := alignKeys(op, 1); err != nil {
+			return err
+		}
+		if err := alignValues(op, values); err != nil {
+			return err
+		}
+		return handler(op)
+	})
+}
+
+// valueAt the i-th value of op as a value of the driver
+func valueAt[V any](op *Operation, i int) (V, error) {
+	value, ok := op.Values[i].(V)
+	if !ok {
+		return value, fmt.Errorf("operation %s value type mismatch: expected %T, got %T", op.Name, value, op.Values[i])
+	}
+	return value, nil
+}
+
+// write run a single item write
+func (m *middlewareDriver[V]) write(name string, key string, value V, ttl time.Duration, fn func(next Driver[V], key string, value V, ttl time.Duration) error) error {
+	return m.single(&Operation{Name: name, Keys: []string{key}, Values: []any{value}, TTL: ttl}, func(op *Operation) error {
+		value, err := valueAt[V](op, 0)
+		if err != nil {
+			return err
+		}
+		return fn(m.next(op), op.Keys[0], value, op.TTL)
+	})
+}
+
+func (m *middlewareDriver[V]) Add(key string, value V, t time.Duration) error {
+	return m.write("Add", key, value, t, Driver[V].Add)
+}
+
+func (m *middlewareDriver[V]) Set(key string, value V, t time.Duration) error {
+	return m.write("Set", key, value, t, Driver[V].Set)
+}
+
+func (m *middlewareDriver[V]) SetMany(many []Many[V]) error {
+	op := &Operation{Name: "SetMany", Keys: make([]string, len(many)), Values: make([]any, len(many))}
+	for i, item := range many {
+		op.Keys[i], op.Values[i] = item.Key, item.Value
+	}
+	return m.run(op, func(op *Operation) error {
+		if err := alignKeys(op, len(many)); err != nil {
+			return err
+		}
+		if err := alignValues(op, len(many)); err != nil {
+			return err
+		}
+		items := make([]Many[V], len(many))
+		for i := range many {
+			value, err := valueAt[V](op, i)
+			if err != nil {
+				return err
+			}
+			items[i] = Many[V]{Key: op.Keys[i], Value: value, TTL: many[i].TTL}
+		}
+		return m.next(op).SetMany(items)
+	})
+}
+
+func (m *middlewareDriver[V]) Forever(key string, value V) error {
+	return m.write("Forever", key, value, 0, func(next Driver[V], key string, value V, _ time.Duration) error {
+		return next.Forever(key, value)
+	})
+}
+
+func (m *middlewareDriver[V]) SetNumber(key string, value V, t time.Duration) error {
+	return m.write("SetNumber", key, value, t, Driver[V].SetNumber)
+}
+
+func (m *middlewareDriver[V]) Forget(key string) error {
+	return m.single(&Operation{Name: "Forget", Keys: []string{key}}, func(op *Operation) error {
+		return m.next(op).Forget(op.Keys[0])
+	})
+}
+
+func (m *middlewareDriver[V]) Del(key string) error {
+	return m.single(&Operation{Name: "Del", Keys: []string{key}}, func(op *Operation) error {
+		return m.next(op).Del(op.Keys[0])
+	})
+}
+
+func (m *middlewareDriver[V]) Flush() error {
+	return m.run(&Operation{Name: "Flush"}, func(op *Operation) error {
+		return m.next(op).Flush()
+	})
+}
+
+func (m *middlewareDriver[V]) Get(key string) (result V, err error) {
+	err = m.single(&Operation{Name: "Get", Keys: []string{key}}, func(op *Operation) error {
+		result, err = m.next(op).Get(op.Keys[0])
+		op.Result = result
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Has(key string) (found bool, err error) {
+	err = m.single(&Operation{Name: "Has", Keys: []string{key}}, func(op *Operation) error {
+		found, err = m.next(op).Has(op.Keys[0])
+		op.Result = found
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Many(keys []string) (items map[string]V, err error) {
+	err = m.run(&Operation{Name: "Many", Keys: cloneKeys(keys)}, func(op *Operation) error {
+		if err := alignKeys(op, len(keys)); err != nil {
+			return err
+		}
+		found, err := m.next(op).Many(op.Keys)
+		items = originItems(keys, op, found)
+		op.Result = items
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) DelMany(keys []string) error {
+	return m.run(&Operation{Name: "DelMany", Keys: cloneKeys(keys)}, func(op *Operation) error {
+		return m.next(op).DelMany(op.Keys)
+	})
+}
+
+func (m *middlewareDriver[V]) ForgetMany(keys []string) error {
+	return m.run(&Operation{Name: "ForgetMany", Keys: cloneKeys(keys)}, func(op *Operation) error {
+		return m.next(op).ForgetMany(op.Keys)
+	})
+}
+
+// step run an Increment or Decrement
+func (m *middlewareDriver[V]) step(name string, key string, n V, fn func(next Driver[V], key string, n V) (V, error)) (ret V, err error) {
+	err = m.single(&Operation{Name: name, Keys: []string{key}, Values: []any{n}}, func(op *Operation) error {
+		n, err := valueAt[V](op, 0)
+		if err != nil {
+			return err
+		}
+		ret, err = fn(m.next(op), op.Keys[0], n)
+		op.Result = ret
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Increment(key string, n V) (V, error) {
+	return m.step("Increment", key, n, Driver[V].Increment)
+}
+
+func (m *middlewareDriver[V]) Decrement(key string, n V) (V, error) {
+	return m.step("Decrement", key, n, Driver[V].Decrement)
+}
+
+// remember run a single key Remember*
+func (m *middlewareDriver[V]) remember(name string, key string, ttl time.Duration, fn func(next Driver[V], key string, ttl time.Duration) (V, error)) (result V, err error) {
+	err = m.single(&Operation{Name: name, Keys: []string{key}, TTL: ttl}, func(op *Operation) error {
+		result, err = fn(m.next(op), op.Keys[0], op.TTL)
+		op.Result = result
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (V, error) {
+	return m.remember("Remember", key, ttl, func(next Driver[V], key string, ttl time.Duration) (V, error) {
+		return next.Remember(key, ttl, callback, force)
+	})
+}
+
+func (m *middlewareDriver[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
+	return m.remember("RememberForever", key, 0, func(next Driver[V], key string, _ time.Duration) (V, error) {
+		return next.RememberForever(key, callback, force)
+	})
+}
+
+func (m *middlewareDriver[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return m.remember("RememberStale", key, freshTTL+staleTTL, func(next Driver[V], key string, _ time.Duration) (V, error) {
+		return next.RememberStale(key, freshTTL, staleTTL, callback)
+	})
+}
+
+func (m *middlewareDriver[V]) RememberMany(keys []string, ttl time.Duration, callback func(notHitKeys []string) (map[string]V, error), force bool) (items map[string]V, err error) {
+	err = m.run(&Operation{Name: "RememberMany", Keys: cloneKeys(keys), TTL: ttl}, func(op *Operation) error {
+		if err := alignKeys(op, len(keys)); err != nil {
+			return err
+		}
+		// the callback loads the caller's keys
+		origins := make(map[string]string, len(keys))
+		rewritten := make(map[string]string, len(keys))
+		for i, key := range op.Keys {
+			origins[key], rewritten[keys[i]] = keys[i], key
+		}
+		found, err := m.next(op).RememberMany(op.Keys, op.TTL, func(notHitKeys []string) (map[string]V, error) {
+			loaded, err := callback(lo.Map(notHitKeys, func(key string, _ int) string {
+				return origins[key]
+			}))
+			if err != nil {
+				return nil, err
+			}
+			items := make(map[string]V, len(loaded))
+			for key, item := range loaded {
+				if key, ok := rewritten[key]; ok {
+					items[key] = item
+				}
+			}
+			return items, nil
+		}, force)
+		items = originItems(keys, op, found)
+		op.Result = items
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) TTL(key string) (ttl time.Duration, err error) {
+	err = m.single(&Operation{Name: "TTL", Keys: []string{key}}, func(op *Operation) error {
+		ttl, err = m.next(op).TTL(op.Keys[0])
+		op.Result = ttl
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Touch(key string, ttl time.Duration) error {
+	return m.single(&Operation{Name: "Touch", Keys: []string{key}, TTL: ttl}, func(op *Operation) error {
+		return m.next(op).Touch(op.Keys[0], op.TTL)
+	})
+}
+
+func (m *middlewareDriver[V]) TouchMany(keys []string, ttl time.Duration) error {
+	return m.run(&Operation{Name: "TouchMany", Keys: cloneKeys(keys), TTL: ttl}, func(op *Operation) error {
+		return m.next(op).TouchMany(op.Keys, op.TTL)
+	})
+}
+
+func (m *middlewareDriver[V]) ExpireAt(key string, at time.Time) error {
+	return m.single(&Operation{Name: "ExpireAt", Keys: []string{key}, TTL: time.Until(at)}, func(op *Operation) error {
+		return m.next(op).ExpireAt(op.Keys[0], at)
+	})
+}
+
+func (m *middlewareDriver[V]) ExpireAtMany(keys []string, at time.Time) error {
+	return m.run(&Operation{Name: "ExpireAtMany", Keys: cloneKeys(keys), TTL: time.Until(at)}, func(op *Operation) error {
+		return m.next(op).ExpireAtMany(op.Keys, at)
+	})
+}
+
+func (m *middlewareDriver[V]) Persist(key string) error {
+	return m.single(&Operation{Name: "Persist", Keys: []string{key}}, func(op *Operation) error {
+		return m.next(op).Persist(op.Keys[0])
+	})
+}
+
+func (m *middlewareDriver[V]) PersistMany(keys []string) error {
+	return m.run(&Operation{Name: "PersistMany", Keys: cloneKeys(keys)}, func(op *Operation) error {
+		return m.next(op).PersistMany(op.Keys)
+	})
+}
+
+func (m *middlewareDriver[V]) GetAndTouch(key string, ttl time.Duration) (result V, err error) {
+	err = m.single(&Operation{Name: "GetAndTouch", Keys: []string{key}, TTL: ttl}, func(op *Operation) error {
+		result, err = m.next(op).GetAndTouch(op.Keys[0], op.TTL)
+		op.Result = result
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Pull(key string) (result V, err error) {
+	err = m.single(&Operation{Name: "Pull", Keys: []string{key}}, func(op *Operation) error {
+		result, err = m.next(op).Pull(op.Keys[0])
+		op.Result = result
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) GetSet(key string, value V, t time.Duration) (result V, err error) {
+	err = m.single(&Operation{Name: "GetSet", Keys: []string{key}, Values: []any{value}, TTL: t}, func(op *Operation) error {
+		value, err := valueAt[V](op, 0)
+		if err != nil {
+			return err
+		}
+		result, err = m.next(op).GetSet(op.Keys[0], value, op.TTL)
+		op.Result = result
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) CompareAndSwap(key string, old, value V, t time.Duration) (swapped bool, err error) {
+	err = m.single(&Operation{Name: "CompareAndSwap", Keys: []string{key}, Values: []any{value}, TTL: t}, func(op *Operation) error {
+		value, err := valueAt[V](op, 0)
+		if err != nil {
+			return err
+		}
+		swapped, err = m.next(op).CompareAndSwap(op.Keys[0], old, value, op.TTL)
+		op.Result = swapped
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (result V, err error) {
+	err = m.single(&Operation{Name: "Update", Keys: []string{key}, TTL: t}, func(op *Operation) error {
+		result, err = m.next(op).Update(op.Keys[0], op.TTL, fn)
+		op.Result = result
+		return err
+	})
+	return
+}
+
+func (m *middlewareDriver[V]) Scan(pattern string, batchSize int, fn func(key string) error) error {
+	return m.run(&Operation{Name: "Scan"}, func(op *Operation) error {
+		return m.next(op).Scan(pattern, batchSize, fn)
+	})
+}
+
+func (m *middlewareDriver[V]) ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error {
+	return m.run(&Operation{Name: "ScanItems"}, func(op *Operation) error {
+		return m.next(op).ScanItems(pattern, batchSize, fn)
+	})
+}
@@ -0,0 +1,270 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMiddlewareDriver(t *testing.T, optionFns ...OptionFunc) (string, *gocache.Cache) {
+	t.Helper()
+	memCache := gocache.New(time.Minute, time.Minute)
+	driverName := nextDriverName("middleware_test")
+	require.NoError(t, RegisterGoCacheDriver(driverName, memCache, "", optionFns...))
+	return driverName, memCache
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(op *Operation, next Handler) error {
+			calls = append(calls, name+" "+op.Name)
+			err := next(op)
+			calls = append(calls, name+" done")
+			return err
+		}
+	}
+	driverName, _ := setupMiddlewareDriver(t, WithMiddlewares(trace("first"), trace("second")), WithMiddlewares(trace("third")))
+
+	for i := 0; i < 2; i++ {
+		calls = nil
+		driver, err := Use[string](driverName)
+		require.NoError(t, err)
+		assert.NoError(t, driver.Set("key", "value", time.Minute))
+		assert.Equal(t, []string{"first Set", "second Set", "third Set", "third done", "second done", "first done"}, calls)
+	}
+}
+
+func TestMiddlewareRewritesOperations(t *testing.T) {
+	namespace := func(op *Operation, next Handler) error {
+		for i, key := range op.Keys {
+			op.Keys[i] = "ns:" + key
+		}
+		return next(op)
+	}
+	upper := func(op *Operation, next Handler) error {
+		for i, value := range op.Values {
+			op.Values[i] = strings.ToUpper(value.(string))
+		}
+		if op.Name == "Set" {
+			op.TTL = time.Hour
+		}
+		return next(op)
+	}
+	driverName, memCache := setupMiddlewareDriver(t, WithMiddlewares(namespace, upper))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	value, found := memCache.Get("ns:key")
+	assert.True(t, found)
+	assert.Equal(t, "VALUE", value)
+	ttl, err := driver.TTL("key")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "VALUE", got)
+
+	assert.NoError(t, driver.SetMany([]Many[string]{{Key: "a", Value: "x", TTL: time.Minute}}))
+	keys := []string{"a"}
+	many, err := driver.Many(keys)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "X"}, many)
+	assert.Equal(t, []string{"a"}, keys)
+
+	var loaded []string
+	many, err = driver.RememberMany([]string{"a", "b"}, time.Minute, func(notHitKeys []string) (map[string]string, error) {
+		loaded = notHitKeys
+		return map[string]string{"b": "y"}, nil
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, loaded)
+	assert.Equal(t, map[string]string{"a": "X", "b": "y"}, many)
+	_, found = memCache.Get("ns:b")
+	assert.True(t, found)
+}
+
+func TestMiddlewareKeysMismatch(t *testing.T) {
+	drop := func(op *Operation, next Handler) error {
+		op.Keys = op.Keys[1:]
+		return next(op)
+	}
+	driverName, _ := setupMiddlewareDriver(t, WithMiddlewares(drop))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	assert.EqualError(t, driver.SetMany([]Many[string]{{Key: "a", Value: "x"}, {Key: "b", Value: "y"}}), "operation SetMany keys mismatch: expected 2 keys, got 1")
+	_, err = driver.Many([]string{"a", "b"})
+	assert.EqualError(t, err, "operation Many keys mismatch: expected 2 keys, got 1")
+	assert.EqualError(t, driver.Set("a", "x", time.Minute), "operation Set keys mismatch: expected 1 keys, got 0")
+	_, err = driver.Get("a")
+	assert.EqualError(t, err, "operation Get keys mismatch: expected 1 keys, got 0")
+	assert.EqualError(t, driver.Forget("a"), "operation Forget keys mismatch: expected 1 keys, got 0")
+
+	dropValues := func(op *Operation, next Handler) error {
+		op.Values = nil
+		return next(op)
+	}
+	driverName, _ = setupMiddlewareDriver(t, WithMiddlewares(dropValues))
+	driver, err = Use[string](driverName)
+	require.NoError(t, err)
+	assert.EqualError(t, driver.Set("a", "x", time.Minute), "operation Set values mismatch: expected 1 values, got 0")
+	_, err = driver.GetSet("a", "x", time.Minute)
+	assert.EqualError(t, err, "operation GetSet values mismatch: expected 1 values, got 0")
+	_, err = driver.Get("a")
+	assert.ErrorIs(t, err, ErrCacheMiss, "the operations without values are unaffected")
+}
+
+func TestMiddlewareReplacesContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	withCtx := func(op *Operation, next Handler) error {
+		op.Ctx = ctx
+		return next(op)
+	}
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	driverName := nextDriverName("middleware_test")
+	require.NoError(t, RegisterRedisDriver(driverName, redis.NewClient(&redis.Options{Addr: mr.Addr()}), "", WithMiddlewares(withCtx)))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, driver.Set("key", "value", time.Minute), context.Canceled)
+	assert.False(t, mr.Exists("key"))
+}
+
+func TestMiddlewareRetriesAndShortCircuits(t *testing.T) {
+	errDenied := errors.New("denied")
+	var attempts int
+	retry := func(op *Operation, next Handler) error {
+		var err error
+		for i := 0; i < 3; i++ {
+			if err = next(op); !errors.Is(err, ErrCacheMiss) {
+				return err
+			}
+			attempts++
+		}
+		return err
+	}
+	deny := func(op *Operation, next Handler) error {
+		if op.Name == "Flush" {
+			return errDenied
+		}
+		return next(op)
+	}
+	driverName, _ := setupMiddlewareDriver(t, WithMiddlewares(deny, retry))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	_, err = driver.Get("missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, 3, attempts)
+	assert.ErrorIs(t, driver.Flush(), errDenied)
+}
+
+func TestMiddlewareResultAndTypeMismatch(t *testing.T) {
+	var results []any
+	record := func(op *Operation, next Handler) error {
+		err := next(op)
+		results = append(results, op.Result)
+		return err
+	}
+	mismatch := func(op *Operation, next Handler) error {
+		if op.Name == "Add" {
+			op.Values[0] = 1
+		}
+		return next(op)
+	}
+	driverName, _ := setupMiddlewareDriver(t, WithMiddlewares(record, mismatch))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	_, err = driver.Get("key")
+	assert.NoError(t, err)
+	_, err = driver.Has("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{nil, "value", true}, results)
+
+	assert.EqualError(t, driver.Add("other", "value", time.Minute), "operation Add value type mismatch: expected string, got int")
+}
+
+func TestMiddlewareDerivedDriversAndHooks(t *testing.T) {
+	var ops []string
+	record := func(op *Operation, next Handler) error {
+		ops = append(ops, op.Name)
+		return next(op)
+	}
+	var calls []string
+	hook := &recordingHook{name: "hook", calls: &calls}
+	driverName, _ := setupMiddlewareDriver(t, WithMiddlewares(record), WithHooks(hook))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	tagged := driver.Tags("tag")
+	assert.NoError(t, tagged.Set("key", "value", time.Minute))
+	assert.NoError(t, tagged.Flush())
+	_, err = driver.WithSerializer(&JSONSerializer{}).Get("key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, []string{"Set", "Flush", "Get"}, ops)
+	assert.Equal(t, []string{"Set", "Flush", "Get"}, []string{hook.events[0].Operation, hook.events[1].Operation, hook.events[2].Operation})
+}
+
+// prefixDriver overrides Get only, every other method is forwarded by DriverWrapper
+type prefixDriver struct {
+	DriverWrapper[string]
+}
+
+func (p *prefixDriver) Get(key string) (string, error) {
+	value, err := p.Next.Get(key)
+	return "got:" + value, err
+}
+
+func TestDriverWrapper(t *testing.T) {
+	driver := setupGoCacheDriver[string](t)
+	var wrap func(next Driver[string]) Driver[string]
+	wrap = func(next Driver[string]) Driver[string] {
+		return &prefixDriver{DriverWrapper[string]{Next: next, Rewrap: wrap}}
+	}
+	wrapped := wrap(driver)
+
+	testCache[string](t, &DriverWrapper[string]{Next: driver}, "key", "value")
+	assert.NoError(t, wrapped.Set("key", "value", time.Minute))
+	got, err := wrapped.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "got:value", got)
+	got, err = wrapped.WithCtx(driver.ctx).Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "got:value", got)
+
+	// the tags and locks of the wrapped driver are available
+	assert.NoError(t, wrapped.Tags("tag").Set("tagged", "value", time.Minute))
+	assert.NoError(t, wrapped.Tags("tag").Flush())
+	has, err := driver.Has("tagged")
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestMiddlewareConcurrentWithCtx(t *testing.T) {
+	driverName, _ := setupMiddlewareDriver(t, WithMiddlewares(func(op *Operation, next Handler) error {
+		if len(op.Keys) == 1 && op.Ctx.Value(ctxKey{}) != op.Keys[0] {
+			return fmt.Errorf("operation %s of %s got the context of %v", op.Name, op.Keys[0], op.Ctx.Value(ctxKey{}))
+		}
+		return next(op)
+	}))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	testConcurrentWithCtx(t, driver.Tags("tag"))
+}
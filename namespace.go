@@ -0,0 +1,164 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultNamespaceRefresh how long the version read from redis is reused by default
+const defaultNamespaceRefresh = time.Second
+
+// NamespaceOptions options of WithVersionedNamespace
+type NamespaceOptions struct {
+	// Sweep delete the redis keys of the older generations in the background after Flush, otherwise they are
+	// left to expire, the go-cache items of the older generations are always swept
+	Sweep bool
+	// Refresh how long a version read from redis is reused, other processes see a Flush after at most
+	// Refresh, 1 second by default, a negative Refresh reads the version for every key
+	Refresh time.Duration
+}
+
+// namespace the versioned namespace of a driver, shared by every Use of the driver
+type namespace struct {
+	NamespaceOptions
+	mu sync.Mutex
+	// version current generation, the memory version of go-cache drivers or the last version read from redis
+	version int64
+	// readAt when version was read from redis
+	readAt time.Time
+}
+
+// namespaceVersionKey the redis key of the version of the namespace of the driver
+func (d *baseDriver) namespaceVersionKey() string {
+	return d.internalKey("namespace:version")
+}
+
+// namespacePrefix the prefix of the keys of a generation of the namespace
+func (d *baseDriver) namespacePrefix(version int64) string {
+	return d.prefix + ":v" + strconv.FormatInt(version, 10)
+}
+
+// namespaceVersion the current generation of the namespace. A stale version read from redis is refreshed
+// in the background while the last known version is used, only the first read, and every read when Refresh
+// is negative, wait for redis. The error of the first read is returned, the last known version is used
+// when a later read fails.
+func (d *baseDriver) namespaceVersion() (int64, error) {
+	ns := d.namespace
+	if d.redisClient == nil {
+		return atomic.LoadInt64(&ns.version), nil
+	}
+	ns.mu.Lock()
+	version, loaded := ns.version, !ns.readAt.IsZero()
+	stale := ns.Refresh < 0 || time.Since(ns.readAt) >= ns.Refresh
+	ns.mu.Unlock()
+	switch {
+	case !stale:
+		return version, nil
+	case loaded && ns.Refresh >= 0:
+		refresher := *d
+		refresher.ctx = detachContext(d.ctx)
+		d.group.DoAsync(d.namespaceVersionKey(), func() (any, error) {
+			version, err := refresher.readNamespaceVersion()
+			return flightResult[int64]{val: version}, refresher.stats.fail(err)
+		})
+		return version, nil
+	}
+	// a shared version read is not a shared load of the items
+	read, err := doFlight(context.Background(), d.group, d.namespaceVersionKey(), d.readNamespaceVersion)
+	if err != nil && loaded {
+		// the operation is likely to fail too, the last known version is used meanwhile
+		_ = d.stats.fail(err)
+		return version, nil
+	}
+	return read, err
+}
+
+// readNamespaceVersion read the version from redis and keep it unless a Flush set a newer one meanwhile
+func (d *baseDriver) readNamespaceVersion() (int64, error) {
+	started := time.Now()
+	read, err := d.redisClient.Get(d.ctx, d.namespaceVersionKey()).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	ns := d.namespace
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if started.Before(ns.readAt) {
+		return ns.version, nil
+	}
+	ns.version, ns.readAt = read, time.Now()
+	return read, nil
+}
+
+// namespaceGeneration the generation of a cache key of the namespace, false for the other keys
+func (d *baseDriver) namespaceGeneration(cacheKey string) (int64, bool) {
+	rest := strings.TrimPrefix(cacheKey, d.prefix+":v")
+	end := strings.IndexByte(rest, ':')
+	if len(rest) == len(cacheKey) || end < 0 {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(rest[:end], 10, 64)
+	return version, err == nil
+}
+
+// flushNamespace start a new generation of the namespace. The go-cache items of every older generation are
+// swept in the background, since the items without expiration would never leave the memory, the redis keys
+// are swept if the namespace sweeps.
+func (d *baseDriver) flushNamespace() error {
+	ns := d.namespace
+	if d.redisClient == nil {
+		version := atomic.AddInt64(&ns.version, 1)
+		go d.sweepMemCache(version)
+		return nil
+	}
+	version, err := d.redisClient.Incr(d.ctx, d.namespaceVersionKey()).Result()
+	if err != nil {
+		return err
+	}
+	ns.mu.Lock()
+	ns.version, ns.readAt = version, time.Now()
+	ns.mu.Unlock()
+	if ns.Sweep {
+		sweeper := *d
+		sweeper.ctx = detachContext(d.ctx)
+		go func() {
+			_ = sweeper.deleteMatchingFunc(escapeGlob(sweeper.prefix)+":v*", func(cacheKey string) bool {
+				generation, ok := sweeper.namespaceGeneration(cacheKey)
+				return ok && generation < version
+			})
+		}()
+	}
+	return nil
+}
+
+// sweepMemCache delete the go-cache items of the generations older than version
+func (d *baseDriver) sweepMemCache(version int64) {
+	for cacheKey := range d.memCache.Items() {
+		if generation, ok := d.namespaceGeneration(cacheKey); ok && generation < version {
+			d.memCache.Delete(cacheKey)
+		}
+	}
+}
+
+// WithVersionedNamespace build the keys as prefix:v{N}:key, Flush increments N in O(1) instead of deleting
+// the keys of the prefix. N is stored in redis by the redis and tiered drivers and in memory by the go-cache driver.
+func WithVersionedNamespace(opts NamespaceOptions) OptionFunc {
+	return func(driver *baseDriver) error {
+		if driver.prefix == "" {
+			return fmt.Errorf("versioned namespace requires a prefix")
+		}
+		if opts.Refresh == 0 {
+			opts.Refresh = defaultNamespaceRefresh
+		}
+		driver.namespace = &namespace{NamespaceOptions: opts}
+		return nil
+	}
+}
@@ -0,0 +1,261 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testVersionedNamespace(t *testing.T, driver Driver[string]) {
+	assert.NoError(t, driver.Set("a", "a", time.Minute))
+	assert.NoError(t, driver.SetMany([]Many[string]{{Key: "b", Value: "b", TTL: time.Minute}}))
+	assert.NoError(t, driver.Flush())
+
+	items, err := driver.Many([]string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	assert.NoError(t, driver.Set("a", "new", time.Minute))
+	got, err := driver.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", got)
+}
+
+func TestRedisVersionedNamespace(t *testing.T) {
+	driver := setupRedisDriverWithPrefix[string](t, "cache_prefix", WithVersionedNamespace(NamespaceOptions{}))
+	testVersionedNamespace(t, driver)
+
+	keys, err := driver.redisClient.Keys(driver.ctx, "*").Result()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"cache_prefix:v0:a", "cache_prefix:v0:b", "cache_prefix:v1:a", "cache_prefix:\x00cacheit:namespace:version"}, keys,
+		"the previous generation is left to expire")
+}
+
+func TestRedisVersionedNamespaceSweep(t *testing.T) {
+	driver := setupRedisDriverWithPrefix[string](t, "cache_prefix", WithVersionedNamespace(NamespaceOptions{Sweep: true}))
+	testVersionedNamespace(t, driver)
+
+	assert.Eventually(t, func() bool {
+		keys, err := driver.redisClient.Keys(driver.ctx, "cache_prefix:v0:*").Result()
+		return err == nil && len(keys) == 0
+	}, time.Second, 10*time.Millisecond)
+	found, err := driver.Has("a")
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestRedisVersionedNamespaceSweepsEveryOlderGeneration(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	use := func(sweep bool) Driver[string] {
+		driverName := nextDriverName("namespace_test")
+		require.NoError(t, RegisterRedisDriver(driverName, client, "shared", WithVersionedNamespace(NamespaceOptions{Sweep: sweep, Refresh: -1})))
+		driver, err := Use[string](driverName)
+		require.NoError(t, err)
+		return driver
+	}
+	keeping, sweeping := use(false), use(true)
+	assert.NoError(t, keeping.Forever("a", "v0"))
+	assert.NoError(t, keeping.Flush())
+	assert.NoError(t, keeping.Forever("a", "v1"))
+	assert.NoError(t, sweeping.Flush())
+	assert.NoError(t, sweeping.Forever("a", "v2"))
+
+	assert.Eventually(t, func() bool {
+		keys, err := client.Keys(context.Background(), "shared:v*").Result()
+		return err == nil && len(keys) == 1 && keys[0] == "shared:v2:a"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisVersionedNamespaceRefreshesInBackground(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	driverName := nextDriverName("namespace_test")
+	require.NoError(t, RegisterRedisDriver(driverName, client, "shared", WithVersionedNamespace(NamespaceOptions{Refresh: 20 * time.Millisecond})))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+
+	// another process flushes
+	require.NoError(t, client.Incr(context.Background(), "shared:\x00cacheit:namespace:version").Err())
+	time.Sleep(30 * time.Millisecond)
+	got, err := driver.Get("key")
+	assert.NoError(t, err, "the last known version is used while it is refreshed")
+	assert.Equal(t, "value", got)
+	assert.Eventually(t, func() bool {
+		_, err := driver.Get("key")
+		return errors.Is(err, ErrCacheMiss)
+	}, time.Second, 10*time.Millisecond)
+
+	mr.SetError("unavailable")
+	time.Sleep(30 * time.Millisecond)
+	_, _ = driver.Get("key")
+	assert.Eventually(t, func() bool {
+		stats, err := GetStats(driverName)
+		return err == nil && stats.Errors >= 2
+	}, time.Second, 10*time.Millisecond, "the failed version reads are counted")
+}
+
+// failingVersionHook fails the reads of the namespace version while failing is set
+type failingVersionHook struct {
+	failing int32
+}
+
+func (h *failingVersionHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if atomic.LoadInt32(&h.failing) == 1 && cmd.Name() == "get" && strings.HasSuffix(fmt.Sprint(cmd.Args()[1]), "namespace:version") {
+		return ctx, errors.New("version unavailable")
+	}
+	return ctx, nil
+}
+
+func (h *failingVersionHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *failingVersionHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *failingVersionHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisVersionedNamespaceFirstReadFails(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+	hook := &failingVersionHook{failing: 1}
+	client.AddHook(hook)
+	require.NoError(t, mr.Set("shared:\x00cacheit:namespace:version", "3"))
+
+	driverName := nextDriverName("namespace_test")
+	require.NoError(t, RegisterTieredDriver(driverName, gocache.New(time.Minute, time.Minute), client, "shared", time.Minute, WithVersionedNamespace(NamespaceOptions{})))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+
+	assert.EqualError(t, driver.Set("key", "value", time.Minute), "version unavailable")
+	_, err = driver.Get("key")
+	assert.EqualError(t, err, "version unavailable")
+	_, err = driver.Remember("key", time.Minute, func() (string, error) {
+		return "value", nil
+	}, false)
+	assert.EqualError(t, err, "version unavailable")
+	assert.Empty(t, lo.Filter(mr.Keys(), func(key string, _ int) bool {
+		return strings.HasPrefix(key, "shared:v")
+	}), "nothing is written to v0")
+	stats, err := GetStats(driverName)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), stats.Errors, "Remember reads the version again after its Get failed")
+
+	atomic.StoreInt32(&hook.failing, 0)
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	assert.True(t, mr.Exists("shared:v3:key"))
+}
+
+func TestRedisVersionedNamespaceAcrossDrivers(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	use := func() Driver[string] {
+		driverName := nextDriverName("namespace_test")
+		require.NoError(t, RegisterRedisDriver(driverName, client, "shared", WithVersionedNamespace(NamespaceOptions{Refresh: -1})))
+		driver, err := Use[string](driverName)
+		require.NoError(t, err)
+		return driver
+	}
+	first, second := use(), use()
+	assert.NoError(t, first.Set("key", "value", time.Minute))
+	got, err := second.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", got)
+
+	assert.NoError(t, second.Flush())
+	_, err = first.Get("key")
+	assert.ErrorIs(t, err, ErrCacheMiss, "a flush by another driver is seen")
+}
+
+func TestGoCacheVersionedNamespaceSweepsWithoutSweep(t *testing.T) {
+	driver := setupGoCacheDriverWithPrefix[string](t, "cache_prefix", WithVersionedNamespace(NamespaceOptions{}))
+	assert.NoError(t, driver.Forever("a", "v0"))
+	assert.NoError(t, driver.Flush())
+	assert.NoError(t, driver.Forever("a", "v1"))
+	assert.NoError(t, driver.Flush())
+	assert.NoError(t, driver.Forever("a", "v2"))
+
+	assert.Eventually(t, func() bool {
+		_, found0 := driver.memCache.Get("cache_prefix:v0:a")
+		_, found1 := driver.memCache.Get("cache_prefix:v1:a")
+		return !found0 && !found1
+	}, time.Second, 10*time.Millisecond)
+	got, err := driver.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", got)
+}
+
+func TestGoCacheVersionedNamespace(t *testing.T) {
+	driver := setupGoCacheDriverWithPrefix[string](t, "cache_prefix", WithVersionedNamespace(NamespaceOptions{Sweep: true}))
+	assert.NoError(t, driver.Set("old", "old", time.Minute))
+	_, found := driver.memCache.Get("cache_prefix:v0:old")
+	assert.True(t, found)
+
+	testVersionedNamespace(t, driver)
+	assert.Eventually(t, func() bool {
+		_, found := driver.memCache.Get("cache_prefix:v0:old")
+		return !found
+	}, time.Second, 10*time.Millisecond)
+	_, found = driver.memCache.Get("cache_prefix:v1:a")
+	assert.True(t, found)
+}
+
+func TestTieredVersionedNamespace(t *testing.T) {
+	driver := setupTieredDriver[string](t, time.Minute, WithVersionedNamespace(NamespaceOptions{}))
+	testVersionedNamespace(t, driver)
+	version, err := driver.redisClient.Get(driver.ctx, "cache_prefix:\x00cacheit:namespace:version").Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+}
+
+func TestVersionedNamespaceRequiresPrefix(t *testing.T) {
+	err := RegisterGoCacheDriver(nextDriverName("namespace_test"), gocache.New(time.Minute, time.Minute), "", WithVersionedNamespace(NamespaceOptions{}))
+	assert.Error(t, err)
+}
+
+func TestVersionedNamespaceLocksAreNotVersioned(t *testing.T) {
+	driverName := nextDriverName("namespace_test")
+	require.NoError(t, RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), "locks", WithVersionedNamespace(NamespaceOptions{})))
+	lock, err := NewLock(driverName, "job", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00cacheit:lock:locks:job", lock.(*memoryLock).key)
+}
@@ -1,12 +1,23 @@
 package cacheit
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/go-redis/redis/v8"
 	gocache "github.com/patrickmn/go-cache"
 )
 
 type OptionFunc func(driver *baseDriver) error
 
+// withName set the name the driver is registered with
+func withName(name string) OptionFunc {
+	return func(driver *baseDriver) error {
+		driver.name = name
+		return nil
+	}
+}
+
 // withPrefix  set a cache prefix
 func withPrefix(prefix string) OptionFunc {
 	return func(driver *baseDriver) error {
@@ -16,7 +27,7 @@ func withPrefix(prefix string) OptionFunc {
 }
 
 // withRedisClient set a redis client
-func withRedisClient(redis *redis.Client) OptionFunc {
+func withRedisClient(redis redis.UniversalClient) OptionFunc {
 	return func(driver *baseDriver) error {
 		driver.redisClient = redis
 		return nil
@@ -30,3 +41,104 @@ func withMemCache(memCache *gocache.Cache) OptionFunc {
 		return nil
 	}
 }
+
+// withLocalTTL set the max ttl of the tiered driver memory copies
+func withLocalTTL(ttl time.Duration) OptionFunc {
+	return func(driver *baseDriver) error {
+		driver.localTTL = ttl
+		return nil
+	}
+}
+
+// WithInvalidationBus keep the go-cache of the driver in sync with other processes through bus,
+// every mutation of the driver is published on the bus.
+func WithInvalidationBus(bus *InvalidationBus) OptionFunc {
+	return func(driver *baseDriver) error {
+		driver.bus = bus
+		return nil
+	}
+}
+
+// WithNegativeTTL cache the items not found by the callbacks of Remember* as tombstones for ttl
+func WithNegativeTTL(ttl time.Duration) OptionFunc {
+	return func(driver *baseDriver) error {
+		if ttl < 0 {
+			return fmt.Errorf("negative ttl must not be negative: %s", ttl)
+		}
+		driver.negativeTTL = ttl
+		return nil
+	}
+}
+
+// WithMemoryStorage set how the go-cache and tiered drivers store the values in memory, StoreValues by default
+func WithMemoryStorage(storage MemoryStorage) OptionFunc {
+	return func(driver *baseDriver) error {
+		if storage < StoreValues || storage > StoreCopies {
+			return fmt.Errorf("invalid memory storage: %d", storage)
+		}
+		driver.memStorage = storage
+		return nil
+	}
+}
+
+// WithTTLJitter add a bounded random jitter to every positive ttl written by Set, Add, SetMany, SetNumber and
+// Remember*, Forever and the items without expiration are left untouched.
+func WithTTLJitter(policy JitterPolicy) OptionFunc {
+	return func(driver *baseDriver) error {
+		if policy.Max < 0 || policy.Ratio < 0 {
+			return fmt.Errorf("ttl jitter must not be negative: max %s, ratio %g", policy.Max, policy.Ratio)
+		}
+		driver.jitterPolicy = &policy
+		return nil
+	}
+}
+
+// WithXFetch recompute the items of Remember before they expire, with a probability rising as their ttl shrinks
+// and their loader is slow. The items are stored with the time their loader took.
+func WithXFetch(policy XFetchPolicy) OptionFunc {
+	return func(driver *baseDriver) error {
+		if policy.Beta < 0 {
+			return fmt.Errorf("xfetch beta must not be negative: %g", policy.Beta)
+		}
+		driver.xfetch = &policy
+		return nil
+	}
+}
+
+// WithChunking split the Many, SetMany, DelMany and RememberMany of redis into chunks of at most
+// policy.Size keys, sent by at most policy.Parallelism at a time.
+func WithChunking(policy ChunkPolicy) OptionFunc {
+	return func(driver *baseDriver) error {
+		if policy.Size <= 0 || policy.Parallelism < 0 {
+			return fmt.Errorf("chunk size must be positive and parallelism not negative: size %d, parallelism %d", policy.Size, policy.Parallelism)
+		}
+		driver.chunkPolicy = &policy
+		return nil
+	}
+}
+
+// WithHooks invoke hooks around every operation of the driver, in their order before the operation
+// and in reverse order after it. Every call appends to the hooks of the driver.
+func WithHooks(hooks ...Hook) OptionFunc {
+	return func(driver *baseDriver) error {
+		driver.hooks = append(driver.hooks, hooks...)
+		return nil
+	}
+}
+
+// WithHashedHookKeys report hashed keys to the hooks instead of the raw keys, which may contain personal data
+func WithHashedHookKeys() OptionFunc {
+	return func(driver *baseDriver) error {
+		driver.hashHookKeys = true
+		return nil
+	}
+}
+
+// WithMiddlewares intercept every operation of the driver with middlewares, the first middleware is the
+// outermost. Every call appends to the middlewares of the driver, hooks see the operations before the middlewares.
+func WithMiddlewares(middlewares ...Middleware) OptionFunc {
+	return func(driver *baseDriver) error {
+		driver.middlewares = append(driver.middlewares, middlewares...)
+		return nil
+	}
+}
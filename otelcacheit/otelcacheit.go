@@ -0,0 +1,92 @@
+// Package otelcacheit OpenTelemetry tracing of cacheit drivers.
+//
+//	_ = cacheit.RegisterRedisDriver("redis", redisClient, "prefix", cacheit.WithHooks(otelcacheit.NewHook()))
+//	driver, _ := cacheit.Use[string]("redis")
+//	value, err := driver.WithCtx(ctx).Get("key") // the span is a child of the span of ctx
+package otelcacheit
+
+import (
+	"context"
+
+	"github.com/feymanlee/cacheit"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/feymanlee/cacheit/otelcacheit"
+
+// Option option of the hook
+type Option func(h *Hook)
+
+// WithTracerProvider use provider instead of the global tracer provider
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(h *Hook) {
+		h.provider = provider
+	}
+}
+
+// WithAttributes add attrs to every span
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(h *Hook) {
+		h.attrs = append(h.attrs, attrs...)
+	}
+}
+
+// Hook cacheit.Hook starting a client span per cache operation
+type Hook struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+	attrs    []attribute.KeyValue
+}
+
+var _ cacheit.Hook = (*Hook)(nil)
+
+// NewHook creates a hook tracing with the global tracer provider unless WithTracerProvider is given
+func NewHook(opts ...Option) *Hook {
+	h := &Hook{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.provider == nil {
+		h.provider = otel.GetTracerProvider()
+	}
+	h.tracer = h.provider.Tracer(instrumentationName)
+	return h
+}
+
+func (h *Hook) BeforeOperation(ctx context.Context, event *cacheit.HookEvent) context.Context {
+	attrs := append([]attribute.KeyValue{
+		attribute.String("cache.operation", event.Operation),
+		attribute.String("cache.driver.name", event.DriverName),
+		attribute.String("cache.driver.type", string(event.DriverType)),
+		attribute.Int("cache.item_count", event.Count),
+	}, h.attrs...)
+	if event.Key != "" {
+		attrs = append(attrs, attribute.String("cache.key", event.Key))
+	}
+	ctx, _ = h.tracer.Start(ctx, "cache."+event.Operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+func (h *Hook) AfterOperation(ctx context.Context, event *cacheit.HookEvent) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if event.Hits+event.Misses > 0 {
+		span.SetAttributes(
+			attribute.Int("cache.hits", event.Hits),
+			attribute.Int("cache.misses", event.Misses),
+		)
+		if event.Count == 1 {
+			span.SetAttributes(attribute.Bool("cache.hit", event.Hits == 1))
+		}
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+}
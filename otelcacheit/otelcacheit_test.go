@@ -0,0 +1,57 @@
+package otelcacheit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/feymanlee/cacheit"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	require.NoError(t, cacheit.RegisterGoCacheDriver("otel_test", gocache.New(time.Minute, time.Minute), "otel",
+		cacheit.WithHooks(NewHook(WithTracerProvider(provider)))))
+	driver, err := cacheit.Use[string]("otel_test")
+	require.NoError(t, err)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	driver = driver.WithCtx(ctx)
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	_, err = driver.Get("missing")
+	assert.ErrorIs(t, err, cacheit.ErrCacheMiss)
+	_, err = driver.Remember("failed", time.Minute, func() (string, error) {
+		return "", errors.New("source down")
+	}, false)
+	assert.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for _, span := range spans[:3] {
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+
+	assert.Equal(t, "cache.Set", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("cache.key", "key"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("cache.driver.name", "otel_test"))
+
+	assert.Equal(t, "cache.Get", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), attribute.Bool("cache.hit", false))
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+
+	assert.Equal(t, "cache.Remember", spans[2].Name())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Len(t, spans[2].Events(), 1)
+}
@@ -0,0 +1,518 @@
+package cacheit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scriptNow the prelude of the scripts reading now in milliseconds from ARGV[1], or from the clock of redis
+// when ARGV[1] is empty, so that every client shares the same clock
+const scriptNow = `
+local now = tonumber(ARGV[1])
+if not now then
+	redis.replicate_commands()
+	local time = redis.call("TIME")
+	now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+end`
+
+var (
+	// fixedWindowScript count n requests in the current window unless the limit would be exceeded,
+	// returns whether they are allowed, the count of the window and its remaining ttl in milliseconds
+	fixedWindowScript = redis.NewScript(`
+local n = tonumber(ARGV[1])
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+if current + n > tonumber(ARGV[3]) then
+	return {0, current, redis.call("PTTL", KEYS[1])}
+end
+current = redis.call("INCRBY", KEYS[1], n)
+if current == n then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return {1, current, redis.call("PTTL", KEYS[1])}`)
+	// slidingWindowScript log n requests at now unless the limit of the window would be exceeded, returns
+	// whether they are allowed, the count of the window and the milliseconds until the log is empty and
+	// until the requests could be allowed
+	slidingWindowScript = redis.NewScript(scriptNow + `
+local window, limit, n = tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed, retry = 0, 0
+if count + n <= limit then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[5] .. ":" .. i)
+	end
+	redis.call("PEXPIRE", KEYS[1], window)
+	count, allowed = count + n, 1
+else
+	local blocking = redis.call("ZRANGE", KEYS[1], count + n - limit - 1, count + n - limit - 1, "WITHSCORES")
+	retry = tonumber(blocking[2]) + window - now
+end
+local reset = 0
+local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+if newest[2] then
+	reset = tonumber(newest[2]) + window - now
+end
+return {allowed, count, reset, retry}`)
+	// slidingCounterScript count n requests in the current fixed window unless the count of the previous window,
+	// weighted by the part of it still in the sliding window, plus the current count would exceed the limit,
+	// returns whether they are allowed, both counts and the milliseconds elapsed in the current window
+	slidingCounterScript = redis.NewScript(scriptNow + `
+local window, limit, n = tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local current = math.floor(now / window)
+local elapsed = now - current * window
+local state = redis.call("HMGET", KEYS[1], "window", "count", "previous")
+local last, count, previous = tonumber(state[1]), tonumber(state[2]) or 0, tonumber(state[3]) or 0
+if last == current - 1 then
+	previous = count
+	count = 0
+elseif last ~= current then
+	count, previous = 0, 0
+end
+local allowed = 0
+if previous * (window - elapsed) <= (limit - count - n) * window then
+	count, allowed = count + n, 1
+	redis.call("HSET", KEYS[1], "window", current, "count", count, "previous", previous)
+	redis.call("PEXPIRE", KEYS[1], 2 * window - elapsed)
+end
+return {allowed, count, previous, elapsed}`)
+	// tokenBucketScript take n tokens from the bucket refilled up to now unless it holds fewer, returns whether
+	// they are taken and the tokens left, the refill time never goes back
+	tokenBucketScript = redis.NewScript(scriptNow + `
+local rate, burst, n = tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= n then
+	tokens, allowed = tokens - n, 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", math.max(ts, now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}`)
+)
+
+// memRateLimitMu serializes the memory rate limiters operations
+var memRateLimitMu sync.Mutex
+
+// RateLimitResult outcome of a rate limited request, e.g. for the X-RateLimit-* headers
+type RateLimitResult struct {
+	// Allowed whether the request is allowed
+	Allowed bool
+	// Limit maximum number of requests of the window, or capacity of the bucket
+	Limit int64
+	// Remaining number of requests still allowed now
+	Remaining int64
+	// ResetAfter time until Remaining is back to Limit
+	ResetAfter time.Duration
+	// RetryAfter time until the denied request could be allowed, 0 if it is allowed
+	RetryAfter time.Duration
+}
+
+// RateLimiter rate limiter of the requests of keys, e.g. users or IPs
+type RateLimiter interface {
+	// Allow Report whether a request of key is allowed and count it if it is.
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+	// AllowN Report whether n requests of key are allowed and count them if they are, n must not exceed the limit.
+	AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error)
+	// Reset Forget the requests of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// NewFixedWindowLimiter creates a limiter named name on the registered driver driverName allowing limit
+// requests per key in each window, the window starts with the first request.
+func NewFixedWindowLimiter(driverName string, name string, limit int64, window time.Duration) (RateLimiter, error) {
+	l, err := newBaseLimiter(driverName, name, limit, window)
+	if err != nil {
+		return nil, err
+	}
+	return &fixedWindowLimiter{l, window}, nil
+}
+
+// NewSlidingWindowLimiter creates a limiter named name on the registered driver driverName allowing limit
+// requests per key in any window, every allowed request is logged until it leaves the window.
+func NewSlidingWindowLimiter(driverName string, name string, limit int64, window time.Duration) (RateLimiter, error) {
+	l, err := newBaseLimiter(driverName, name, limit, window)
+	if err != nil {
+		return nil, err
+	}
+	return &slidingWindowLimiter{l, window}, nil
+}
+
+// NewSlidingWindowCounterLimiter creates a limiter named name on the registered driver driverName allowing about
+// limit requests per key in any window. Unlike NewSlidingWindowLimiter it keeps two counters per key whatever the
+// limit, the requests of the previous fixed window are assumed to be evenly spread over it.
+func NewSlidingWindowCounterLimiter(driverName string, name string, limit int64, window time.Duration) (RateLimiter, error) {
+	if window < time.Millisecond {
+		return nil, fmt.Errorf("rate limiter window must be at least 1ms: %s", window)
+	}
+	l, err := newBaseLimiter(driverName, name, limit, window)
+	if err != nil {
+		return nil, err
+	}
+	return &slidingCounterLimiter{l, window}, nil
+}
+
+// NewTokenBucketLimiter creates a limiter named name on the registered driver driverName whose buckets hold
+// up to burst tokens and are refilled with rate tokens per second, every request takes a token.
+func NewTokenBucketLimiter(driverName string, name string, rate float64, burst int64) (RateLimiter, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("rate limiter rate must be positive: %g", rate)
+	}
+	l, err := newBaseLimiter(driverName, name, burst, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenBucketLimiter{l, rate}, nil
+}
+
+func newBaseLimiter(driverName string, name string, limit int64, period time.Duration) (baseLimiter, error) {
+	value, ok := registerDrivers.Load(driverName)
+	if !ok {
+		return baseLimiter{}, fmt.Errorf("cached driver: %s not registered", driverName)
+	}
+	if limit <= 0 || period <= 0 {
+		return baseLimiter{}, fmt.Errorf("rate limiter limit and window must be positive: %d, %s", limit, period)
+	}
+	return baseLimiter{driver: value.(*baseDriver), name: name, limit: limit}, nil
+}
+
+type baseLimiter struct {
+	driver *baseDriver
+	name   string
+	limit  int64
+	// now clock of the limiter, time.Now by default, the redis scripts read the clock of redis unless it is set
+	now func() time.Time
+}
+
+// clock the current time of the memory limiters
+func (l *baseLimiter) clock() time.Time {
+	if l.now == nil {
+		return time.Now()
+	}
+	return l.now()
+}
+
+// scriptNow the now argument of the scripts, empty to read the clock of redis
+func (l *baseLimiter) scriptNow() any {
+	if l.now == nil {
+		return ""
+	}
+	return l.now().UnixMilli()
+}
+
+// key the cache key of the requests of key
+func (l *baseLimiter) key(key string) string {
+	return l.driver.internalKey("ratelimit:" + l.name + ":" + key)
+}
+
+// check validate the number of requests of AllowN
+func (l *baseLimiter) check(n int64) error {
+	if n <= 0 || n > l.limit {
+		return fmt.Errorf("rate limiter requests must be between 1 and the limit %d: %d", l.limit, n)
+	}
+	return nil
+}
+
+func (l *baseLimiter) Reset(ctx context.Context, key string) error {
+	if l.driver.redisClient == nil {
+		memRateLimitMu.Lock()
+		defer memRateLimitMu.Unlock()
+		l.driver.memState().Delete(l.key(key))
+		return nil
+	}
+	return l.driver.redisClient.Del(ctx, l.key(key)).Err()
+}
+
+// result build the result of a request from the remaining count of the limiter
+func (l *baseLimiter) result(allowed bool, remaining int64, resetAfter, retryAfter time.Duration) RateLimitResult {
+	if allowed {
+		retryAfter = 0
+	}
+	return RateLimitResult{
+		Allowed:    allowed,
+		Limit:      l.limit,
+		Remaining:  remaining,
+		ResetAfter: resetAfter,
+		RetryAfter: retryAfter,
+	}
+}
+
+// fixedWindowLimiter counts the requests of fixed windows, INCRBY and PEXPIRE in a script on redis
+type fixedWindowLimiter struct {
+	baseLimiter
+	window time.Duration
+}
+
+// fixedWindow the memory state of a fixed window
+type fixedWindow struct {
+	count   int64
+	resetAt time.Time
+}
+
+func (l *fixedWindowLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+func (l *fixedWindowLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error) {
+	if err := l.check(n); err != nil {
+		return RateLimitResult{}, err
+	}
+	if l.driver.redisClient == nil {
+		return l.allowMemory(key, n), nil
+	}
+	res, err := fixedWindowScript.Run(ctx, l.driver.redisClient, []string{l.key(key)}, n, l.window.Milliseconds(), l.limit).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	var resetAfter time.Duration
+	if res[2] > 0 {
+		resetAfter = time.Duration(res[2]) * time.Millisecond
+	}
+	return l.result(res[0] == 1, l.limit-res[1], resetAfter, resetAfter), nil
+}
+
+func (l *fixedWindowLimiter) allowMemory(key string, n int64) RateLimitResult {
+	memRateLimitMu.Lock()
+	defer memRateLimitMu.Unlock()
+	now := l.clock()
+	state, _ := l.driver.memState().Get(l.key(key))
+	window, ok := state.(*fixedWindow)
+	if !ok || !now.Before(window.resetAt) {
+		window = &fixedWindow{resetAt: now.Add(l.window)}
+	}
+	allowed := window.count+n <= l.limit
+	if allowed {
+		window.count += n
+		l.driver.memState().Set(l.key(key), window, window.resetAt.Sub(now))
+	}
+	resetAfter := window.resetAt.Sub(now)
+	if window.count == 0 {
+		resetAfter = 0
+	}
+	return l.result(allowed, l.limit-window.count, resetAfter, resetAfter)
+}
+
+// slidingWindowLimiter logs the requests of a sliding window, in a sorted set on redis
+type slidingWindowLimiter struct {
+	baseLimiter
+	window time.Duration
+}
+
+// slidingWindow the memory state of a sliding window, the times of the logged requests in order
+type slidingWindow struct {
+	hits []time.Time
+}
+
+func (l *slidingWindowLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+func (l *slidingWindowLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error) {
+	if err := l.check(n); err != nil {
+		return RateLimitResult{}, err
+	}
+	if l.driver.redisClient == nil {
+		return l.allowMemory(key, n), nil
+	}
+	id, err := randomID()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	res, err := slidingWindowScript.Run(ctx, l.driver.redisClient, []string{l.key(key)},
+		l.scriptNow(), l.window.Milliseconds(), l.limit, n, id).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return l.result(res[0] == 1, l.limit-res[1], time.Duration(res[2])*time.Millisecond, time.Duration(res[3])*time.Millisecond), nil
+}
+
+func (l *slidingWindowLimiter) allowMemory(key string, n int64) RateLimitResult {
+	memRateLimitMu.Lock()
+	defer memRateLimitMu.Unlock()
+	now := l.clock()
+	state, _ := l.driver.memState().Get(l.key(key))
+	window, ok := state.(*slidingWindow)
+	if !ok {
+		window = &slidingWindow{}
+	}
+	for len(window.hits) > 0 && !window.hits[0].After(now.Add(-l.window)) {
+		window.hits = window.hits[1:]
+	}
+	count := int64(len(window.hits))
+	allowed := count+n <= l.limit
+	var retryAfter time.Duration
+	if allowed {
+		for i := int64(0); i < n; i++ {
+			window.hits = append(window.hits, now)
+		}
+		count += n
+		l.driver.memState().Set(l.key(key), window, l.window)
+	} else {
+		retryAfter = window.hits[count+n-l.limit-1].Add(l.window).Sub(now)
+	}
+	var resetAfter time.Duration
+	if count > 0 {
+		resetAfter = window.hits[count-1].Add(l.window).Sub(now)
+	}
+	return l.result(allowed, l.limit-count, resetAfter, retryAfter)
+}
+
+// slidingCounterLimiter approximates a sliding window with the counters of the current and previous fixed
+// windows, in a hash on redis
+type slidingCounterLimiter struct {
+	baseLimiter
+	window time.Duration
+}
+
+// slidingCounter the memory state of a sliding window counter
+type slidingCounter struct {
+	// window index of the current fixed window since the epoch
+	window   int64
+	count    int64
+	previous int64
+}
+
+func (l *slidingCounterLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+func (l *slidingCounterLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error) {
+	if err := l.check(n); err != nil {
+		return RateLimitResult{}, err
+	}
+	if l.driver.redisClient == nil {
+		return l.allowMemory(key, n), nil
+	}
+	res, err := slidingCounterScript.Run(ctx, l.driver.redisClient, []string{l.key(key)},
+		l.scriptNow(), l.window.Milliseconds(), l.limit, n).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return l.counterResult(res[0] == 1, res[1], res[2], res[3], n), nil
+}
+
+func (l *slidingCounterLimiter) allowMemory(key string, n int64) RateLimitResult {
+	memRateLimitMu.Lock()
+	defer memRateLimitMu.Unlock()
+	window, now := l.window.Milliseconds(), l.clock().UnixMilli()
+	current, elapsed := now/window, now%window
+	state, _ := l.driver.memState().Get(l.key(key))
+	counter, ok := state.(*slidingCounter)
+	switch {
+	case !ok:
+		counter = &slidingCounter{window: current}
+	case counter.window == current-1:
+		counter = &slidingCounter{window: current, previous: counter.count}
+	case counter.window != current:
+		counter = &slidingCounter{window: current}
+	}
+	allowed := counter.previous*(window-elapsed) <= (l.limit-counter.count-n)*window
+	if allowed {
+		counter.count += n
+		l.driver.memState().Set(l.key(key), counter, time.Duration(2*window-elapsed)*time.Millisecond)
+	}
+	return l.counterResult(allowed, counter.count, counter.previous, elapsed, n)
+}
+
+// counterResult the result of a request from the counts of the current and previous windows, elapsed
+// milliseconds after the start of the current window
+func (l *slidingCounterLimiter) counterResult(allowed bool, count, previous, elapsed, n int64) RateLimitResult {
+	window := l.window.Milliseconds()
+	ceilDiv := func(a, b int64) int64 {
+		return (a + b - 1) / b
+	}
+	remaining := l.limit - count - ceilDiv(previous*(window-elapsed), window)
+	if remaining < 0 {
+		remaining = 0
+	}
+	var resetAfter, retryAfter int64
+	switch {
+	case count > 0:
+		resetAfter = 2*window - elapsed
+	case previous > 0:
+		resetAfter = window - elapsed
+	}
+	if !allowed {
+		if free := l.limit - count - n; free >= 0 {
+			// the weight of the previous window decreases until the requests fit
+			retryAfter = ceilDiv(window*(previous-free), previous) - elapsed
+		} else {
+			// the current window has to become the previous one first
+			retryAfter = window - elapsed + ceilDiv(window*(count+n-l.limit), count)
+		}
+	}
+	return l.result(allowed, remaining, time.Duration(resetAfter)*time.Millisecond, time.Duration(retryAfter)*time.Millisecond)
+}
+
+// tokenBucketLimiter takes the requests from token buckets, in a hash on redis
+type tokenBucketLimiter struct {
+	baseLimiter
+	// rate tokens refilled per second
+	rate float64
+}
+
+// tokenBucket the memory state of a token bucket
+type tokenBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func (l *tokenBucketLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+func (l *tokenBucketLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error) {
+	if err := l.check(n); err != nil {
+		return RateLimitResult{}, err
+	}
+	if l.driver.redisClient == nil {
+		return l.allowMemory(key, n), nil
+	}
+	res, err := tokenBucketScript.Run(ctx, l.driver.redisClient, []string{l.key(key)},
+		l.scriptNow(), l.rate/1000, l.limit, n).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return l.bucketResult(res[0] == int64(1), tokens, n), nil
+}
+
+func (l *tokenBucketLimiter) allowMemory(key string, n int64) RateLimitResult {
+	memRateLimitMu.Lock()
+	defer memRateLimitMu.Unlock()
+	now := l.clock()
+	state, _ := l.driver.memState().Get(l.key(key))
+	bucket, ok := state.(*tokenBucket)
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.limit), at: now}
+	}
+	if elapsed := now.Sub(bucket.at); elapsed > 0 {
+		bucket.tokens = math.Min(float64(l.limit), bucket.tokens+elapsed.Seconds()*l.rate)
+		bucket.at = now
+	}
+	allowed := bucket.tokens >= float64(n)
+	if allowed {
+		bucket.tokens -= float64(n)
+	}
+	result := l.bucketResult(allowed, bucket.tokens, n)
+	l.driver.memState().Set(l.key(key), bucket, result.ResetAfter+time.Millisecond)
+	return result
+}
+
+// bucketResult the result of a request leaving tokens in the bucket
+func (l *tokenBucketLimiter) bucketResult(allowed bool, tokens float64, n int64) RateLimitResult {
+	refill := func(missing float64) time.Duration {
+		return time.Duration(math.Ceil(missing / l.rate * float64(time.Second)))
+	}
+	return l.result(allowed, int64(math.Floor(tokens)), refill(float64(l.limit)-tokens), refill(float64(n)-tokens))
+}
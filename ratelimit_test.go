@@ -0,0 +1,263 @@
+package cacheit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useClock make the limiter read now, a clock advanced by the tests
+func useClock(limiter RateLimiter, now func() time.Time) {
+	switch l := limiter.(type) {
+	case *fixedWindowLimiter:
+		l.now = now
+	case *slidingWindowLimiter:
+		l.now = now
+	case *slidingCounterLimiter:
+		l.now = now
+	case *tokenBucketLimiter:
+		l.now = now
+	}
+}
+
+func testRateLimiter(t *testing.T, driverName string, fastForward func(time.Duration)) {
+	ctx := context.Background()
+	current := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	now := func() time.Time {
+		return current
+	}
+	advance := func(d time.Duration) {
+		current = current.Add(d)
+		fastForward(d)
+	}
+	allow := func(limiter RateLimiter, key string, n int64) RateLimitResult {
+		t.Helper()
+		result, err := limiter.AllowN(ctx, key, n)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("fixed window", func(t *testing.T) {
+		limiter, err := NewFixedWindowLimiter(driverName, "fixed", 3, time.Minute)
+		require.NoError(t, err)
+		useClock(limiter, now)
+
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: time.Minute}, allow(limiter, "user", 1))
+		advance(20 * time.Second)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: 40 * time.Second}, allow(limiter, "user", 2))
+		assert.Equal(t, RateLimitResult{Limit: 3, ResetAfter: 40 * time.Second, RetryAfter: 40 * time.Second}, allow(limiter, "user", 1))
+		assert.True(t, allow(limiter, "other", 3).Allowed, "keys are limited independently")
+
+		_, err = limiter.AllowN(ctx, "user", 4)
+		assert.Error(t, err)
+		_, err = limiter.AllowN(ctx, "user", 0)
+		assert.Error(t, err)
+
+		advance(40 * time.Second)
+		result, err := limiter.Allow(ctx, "user")
+		assert.NoError(t, err)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: time.Minute}, result)
+
+		assert.NoError(t, limiter.Reset(ctx, "other"))
+		assert.Equal(t, int64(2), allow(limiter, "other", 1).Remaining)
+	})
+	t.Run("sliding window", func(t *testing.T) {
+		limiter, err := NewSlidingWindowLimiter(driverName, "sliding", 2, time.Minute)
+		require.NoError(t, err)
+		useClock(limiter, now)
+
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Minute}, allow(limiter, "user", 1))
+		assert.Equal(t, RateLimitResult{Limit: 2, Remaining: 1, ResetAfter: time.Minute, RetryAfter: time.Minute}, allow(limiter, "user", 2))
+		advance(30 * time.Second)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Minute}, allow(limiter, "user", 1))
+		assert.Equal(t, RateLimitResult{Limit: 2, Remaining: 0, ResetAfter: time.Minute, RetryAfter: 30 * time.Second}, allow(limiter, "user", 1))
+
+		advance(30 * time.Second)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Minute}, allow(limiter, "user", 1),
+			"the first request left the window")
+
+		assert.NoError(t, limiter.Reset(ctx, "user"))
+		assert.Equal(t, int64(0), allow(limiter, "user", 2).Remaining)
+	})
+	t.Run("sliding window counter", func(t *testing.T) {
+		limiter, err := NewSlidingWindowCounterLimiter(driverName, "counter", 4, time.Minute)
+		require.NoError(t, err)
+		useClock(limiter, now)
+
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 4, Remaining: 1, ResetAfter: 2 * time.Minute}, allow(limiter, "user", 3))
+		assert.Equal(t, RateLimitResult{Limit: 4, Remaining: 1, ResetAfter: 2 * time.Minute, RetryAfter: 80 * time.Second}, allow(limiter, "user", 2),
+			"the requests wait for the next window")
+		advance(time.Minute)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 4, Remaining: 0, ResetAfter: 2 * time.Minute}, allow(limiter, "user", 1),
+			"the previous window weighs fully at its end")
+		advance(30 * time.Second)
+		assert.Equal(t, RateLimitResult{Limit: 4, Remaining: 1, ResetAfter: 90 * time.Second, RetryAfter: 10 * time.Second}, allow(limiter, "user", 2),
+			"the previous window weighs half in the middle of the current one")
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 4, Remaining: 0, ResetAfter: 90 * time.Second}, allow(limiter, "user", 1))
+
+		advance(3 * time.Minute)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 4, Remaining: 0, ResetAfter: 90 * time.Second}, allow(limiter, "user", 4),
+			"the counts of older windows are dropped")
+		assert.NoError(t, limiter.Reset(ctx, "user"))
+		assert.Equal(t, int64(3), allow(limiter, "user", 1).Remaining)
+	})
+	t.Run("token bucket", func(t *testing.T) {
+		limiter, err := NewTokenBucketLimiter(driverName, "bucket", 1, 2)
+		require.NoError(t, err)
+		useClock(limiter, now)
+
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: time.Second}, allow(limiter, "user", 1))
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: 2 * time.Second}, allow(limiter, "user", 1))
+		assert.Equal(t, RateLimitResult{Limit: 2, ResetAfter: 2 * time.Second, RetryAfter: time.Second}, allow(limiter, "user", 1))
+
+		advance(500 * time.Millisecond)
+		assert.Equal(t, RateLimitResult{Limit: 2, ResetAfter: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}, allow(limiter, "user", 1))
+		advance(500 * time.Millisecond)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: 2 * time.Second}, allow(limiter, "user", 1))
+
+		advance(time.Minute)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: 2 * time.Second}, allow(limiter, "user", 2),
+			"the bucket holds at most burst tokens")
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		limiter, err := NewFixedWindowLimiter(driverName, "concurrent", 5, time.Minute)
+		require.NoError(t, err)
+
+		var (
+			wg      sync.WaitGroup
+			allowed int64
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if result, err := limiter.Allow(ctx, "user"); err == nil && result.Allowed {
+					atomic.AddInt64(&allowed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(5), atomic.LoadInt64(&allowed))
+	})
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+	driverName := nextDriverName("redis_ratelimit")
+	require.NoError(t, RegisterRedisDriver(driverName, client, "limit_prefix"))
+
+	testRateLimiter(t, driverName, mr.FastForward)
+
+	limiter, err := NewFixedWindowLimiter(driverName, "keys", 1, time.Minute)
+	require.NoError(t, err)
+	_, err = limiter.Allow(context.Background(), "user")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, mr.TTL("limit_prefix:\x00cacheit:ratelimit:keys:user"))
+}
+
+func TestRedisRateLimiterUsesServerTime(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+	driverName := nextDriverName("redis_ratelimit")
+	require.NoError(t, RegisterRedisDriver(driverName, client, "limit_prefix"))
+
+	ctx := context.Background()
+	current := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	mr.SetTime(current)
+	sliding, err := NewSlidingWindowLimiter(driverName, "sliding", 1, time.Minute)
+	require.NoError(t, err)
+	counter, err := NewSlidingWindowCounterLimiter(driverName, "counter", 1, time.Minute)
+	require.NoError(t, err)
+	bucket, err := NewTokenBucketLimiter(driverName, "bucket", 1, 1)
+	require.NoError(t, err)
+	limiters := []RateLimiter{sliding, counter, bucket}
+	for _, limiter := range limiters {
+		result, err := limiter.Allow(ctx, "user")
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		result, err = limiter.Allow(ctx, "user")
+		assert.NoError(t, err)
+		assert.False(t, result.Allowed)
+	}
+
+	mr.SetTime(current.Add(2 * time.Minute))
+	for _, limiter := range limiters {
+		result, err := limiter.Allow(ctx, "user")
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed, "the clock of redis moved")
+	}
+
+	// a client clock behind the one of redis does not refill the bucket twice
+	useClock(bucket, func() time.Time {
+		return current
+	})
+	result, err := bucket.Allow(ctx, "user")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	useClock(bucket, nil)
+	result, err = bucket.Allow(ctx, "user")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	driverName := nextDriverName("mem_ratelimit")
+	require.NoError(t, RegisterGoCacheDriver(driverName, memCache, "limit_prefix"))
+
+	testRateLimiter(t, driverName, func(time.Duration) {})
+
+	limiter, err := NewFixedWindowLimiter(driverName, "keys", 1, time.Minute)
+	require.NoError(t, err)
+	_, err = limiter.Allow(context.Background(), "user")
+	assert.NoError(t, err)
+	state, found := memStates.Load(memCache)
+	require.True(t, found)
+	_, found = state.(*gocache.Cache).Get("limit_prefix:\x00cacheit:ratelimit:keys:user")
+	assert.True(t, found)
+
+	// the limits are kept out of the go-cache of the driver
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	memCache.Flush()
+	assert.NoError(t, driver.Flush())
+	result, err := limiter.Allow(context.Background(), "user")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestNewRateLimiterErrors(t *testing.T) {
+	_, err := NewFixedWindowLimiter("non_existing_driver", "name", 1, time.Minute)
+	assert.Error(t, err)
+
+	driverName := nextDriverName("mem_ratelimit")
+	require.NoError(t, RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), ""))
+	_, err = NewSlidingWindowLimiter(driverName, "name", 0, time.Minute)
+	assert.Error(t, err)
+	_, err = NewFixedWindowLimiter(driverName, "name", 1, 0)
+	assert.Error(t, err)
+	_, err = NewTokenBucketLimiter(driverName, "name", 0, 1)
+	assert.Error(t, err)
+	_, err = NewSlidingWindowCounterLimiter(driverName, "name", 1, time.Microsecond)
+	assert.Error(t, err)
+}
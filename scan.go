@@ -0,0 +1,126 @@
+package cacheit
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrStopScan returned by the callbacks of Scan and ScanItems to stop the scan, the scan then returns nil
+var ErrStopScan = errors.New("stop scan")
+
+// defaultScanBatchSize number of keys fetched at a time by the scans whose batch size is not positive
+const defaultScanBatchSize = 100
+
+// scanPrefix the prefix stripped from the cache keys reported by the scans, in the versioned namespace if any
+func (d *baseDriver) scanPrefix() (string, error) {
+	if d.namespace != nil {
+		version, err := d.namespaceVersion()
+		if err != nil {
+			return "", err
+		}
+		return d.namespacePrefix(version) + ":", nil
+	}
+	if d.prefix == "" {
+		return "", nil
+	}
+	return d.prefix + ":", nil
+}
+
+// scanKey the key of a cache key reported by a scan, false if it is outside of prefix or is an internal key
+func scanKey(prefix string, cacheKey string) (string, bool) {
+	if !strings.HasPrefix(cacheKey, prefix) {
+		return "", false
+	}
+	key := cacheKey[len(prefix):]
+	return key, !strings.HasPrefix(key, internalKeyPrefix)
+}
+
+// escapeGlob escape the glob special characters of s
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// globMatch report whether s matches the redis glob pattern: *, ?, [abc], [^abc], [a-z] and \ escapes
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// an unterminated class matches itself
+				if s[0] != '[' {
+					return false
+				}
+				break
+			}
+			if !classMatch(pattern[1:end+1], s[0]) {
+				return false
+			}
+			pattern = pattern[end+1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// classMatch report whether c is in the glob class, the content of [...]
+func classMatch(class string, c byte) bool {
+	negate := strings.HasPrefix(class, "^")
+	if negate {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		switch {
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			matched = matched || class[i] == c
+		case i+2 < len(class) && class[i+1] == '-':
+			low, high := class[i], class[i+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || low <= c && c <= high
+			i += 2
+		default:
+			matched = matched || class[i] == c
+		}
+	}
+	return matched != negate
+}
@@ -0,0 +1,153 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobMatch(t *testing.T) {
+	for _, c := range []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"*", "", true},
+		{"*", "user:1", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"*:1", "user:1", true},
+		{"u?er", "user", true},
+		{"u?er", "uer", false},
+		{"user:[12]", "user:2", true},
+		{"user:[12]", "user:3", false},
+		{"user:[^12]", "user:3", true},
+		{"user:[0-9]", "user:7", true},
+		{"user:[0-9]", "user:x", false},
+		{`user\*`, "user*", true},
+		{`user\*`, "users", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"[", "[", true},
+	} {
+		assert.Equal(t, c.match, globMatch(c.pattern, c.s), "%q %q", c.pattern, c.s)
+	}
+	assert.Equal(t, `a\*b\?\[c\]\\`, escapeGlob(`a*b?[c]\`))
+}
+
+// scanKeys the sorted keys reported by Scan
+func scanKeys(t *testing.T, driver Driver[string], pattern string) []string {
+	t.Helper()
+	var keys []string
+	assert.NoError(t, driver.Scan(pattern, 2, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	sort.Strings(keys)
+	return keys
+}
+
+func testScan(t *testing.T, driver Driver[string], driverName string) {
+	assert.NoError(t, driver.Set("user:1", "john", time.Hour))
+	assert.NoError(t, driver.Set("user:2", "jane", time.Hour))
+	assert.NoError(t, driver.Forever("order:1", "book"))
+	assert.NoError(t, driver.Tags("users").Set("user:3", "jack", time.Hour))
+	limiter, err := NewFixedWindowLimiter(driverName, "api", 10, time.Minute)
+	require.NoError(t, err)
+	_, err = limiter.Allow(context.Background(), "user:1")
+	assert.NoError(t, err)
+	lock, err := NewLock(driverName, "user:1", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Acquire())
+	// user keys named like the bookkeeping keys are reported
+	internalLike := []string{"namespace:version", "ratelimit:api:user:1", "tag:users:entries"}
+	for _, key := range internalLike {
+		assert.NoError(t, driver.Set(key, key, time.Hour))
+	}
+
+	assert.Equal(t, []string{"user:1", "user:2", "user:3"}, scanKeys(t, driver, "user:*"))
+	assert.Equal(t, []string{"namespace:version", "order:1", "ratelimit:api:user:1", "tag:users:entries", "user:1", "user:2", "user:3"}, scanKeys(t, driver, ""),
+		"tag indexes, rate limiters and locks are not reported")
+	assert.NoError(t, driver.DelMany(internalLike))
+
+	items := make(map[string]string)
+	assert.NoError(t, driver.ScanItems("*", 10, func(key string, value string, ttl time.Duration) error {
+		items[key] = value
+		if key == "order:1" {
+			assert.Equal(t, NoExpirationTTL, ttl)
+		} else {
+			assert.Greater(t, ttl, time.Hour-time.Minute)
+			assert.LessOrEqual(t, ttl, time.Hour)
+		}
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"user:1": "john", "user:2": "jane", "user:3": "jack", "order:1": "book"}, items)
+
+	calls := 0
+	assert.NoError(t, driver.Scan("*", 1, func(key string) error {
+		calls++
+		return ErrStopScan
+	}))
+	assert.Equal(t, 1, calls)
+	scanErr := errors.New("scan failed")
+	assert.ErrorIs(t, driver.ScanItems("*", 1, func(string, string, time.Duration) error {
+		return scanErr
+	}), scanErr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, driver.WithCtx(ctx).Scan("*", 10, func(string) error {
+		return nil
+	}), context.Canceled)
+
+	assert.NoError(t, driver.Flush())
+	assert.Empty(t, scanKeys(t, driver, "*"))
+}
+
+func TestRedisScan(t *testing.T) {
+	driver := setupRedisDriver[string](t)
+	testScan(t, driver, driver.name)
+
+	hash, err := UseHash[string](driver.name)
+	require.NoError(t, err)
+	assert.NoError(t, hash.HSet("profile", "name", "john"))
+	assert.NoError(t, driver.Set("user:1", "john", time.Hour))
+	assert.Equal(t, []string{"profile", "user:1"}, scanKeys(t, driver, "*"))
+	var keys []string
+	assert.NoError(t, driver.ScanItems("*", 10, func(key string, _ string, _ time.Duration) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"user:1"}, keys, "hashes are skipped")
+}
+
+func TestRedisScanVersionedNamespace(t *testing.T) {
+	driver := setupRedisDriverWithPrefix[string](t, "cache_prefix", WithVersionedNamespace(NamespaceOptions{}))
+	testScan(t, driver, driver.name)
+
+	assert.NoError(t, driver.Set("key", "value", time.Hour))
+	assert.Equal(t, []string{"key"}, scanKeys(t, driver, "*"), "the namespace version is not reported")
+}
+
+func TestGoCacheScan(t *testing.T) {
+	driver := setupGoCacheDriver[string](t)
+	testScan(t, driver, driver.name)
+
+	assert.NoError(t, driver.Set("key", "value", time.Hour))
+	driver.memCache.Set("other:key", "value", time.Hour)
+	assert.Equal(t, []string{"key"}, scanKeys(t, driver, "*"), "keys outside of the prefix are not reported")
+}
+
+func TestGoCacheScanVersionedNamespace(t *testing.T) {
+	driver := setupGoCacheDriverWithPrefix[string](t, "cache_prefix", WithVersionedNamespace(NamespaceOptions{}))
+	testScan(t, driver, driver.name)
+}
+
+func TestTieredScan(t *testing.T) {
+	driver := setupTieredDriver[string](t, time.Minute)
+	testScan(t, driver, driver.name)
+}
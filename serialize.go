@@ -1,7 +1,12 @@
 package cacheit
 
 import (
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 // Serializer serializer interface
@@ -22,3 +27,61 @@ func (d *JSONSerializer) Serialize(v any) ([]byte, error) {
 func (d *JSONSerializer) UnSerialize(data []byte, v any) error {
 	return json.Unmarshal(data, v)
 }
+
+// GobSerializer gob serializer, keeps the exact types of the values, interface values must be registered with gob.Register
+type GobSerializer struct{}
+
+// Serialize Serialize data
+func (d *GobSerializer) Serialize(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnSerialize UnSerialize data
+func (d *GobSerializer) UnSerialize(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// MsgpackSerializer MessagePack serializer, more compact and faster than json, time.Time values are decoded in the local time zone
+type MsgpackSerializer struct{}
+
+// Serialize Serialize data
+func (d *MsgpackSerializer) Serialize(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// UnSerialize UnSerialize data
+func (d *MsgpackSerializer) UnSerialize(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// RawSerializer passthrough serializer of []byte and string values, which are stored as is
+type RawSerializer struct{}
+
+// Serialize Serialize data
+func (d *RawSerializer) Serialize(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("raw serializer: unsupported type %T", v)
+	}
+}
+
+// UnSerialize UnSerialize data
+func (d *RawSerializer) UnSerialize(data []byte, v any) error {
+	switch v := v.(type) {
+	case *[]byte:
+		*v = append([]byte{}, data...)
+	case *string:
+		*v = string(data)
+	default:
+		return fmt.Errorf("raw serializer: unsupported type %T", v)
+	}
+	return nil
+}
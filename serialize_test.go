@@ -41,3 +41,87 @@ func TestJSONSerializer(t *testing.T) {
 		assert.Equal(t, testStructData, unSerializedData, "Serialized and UnSerialized data should be equal")
 	})
 }
+
+// fidelityStruct values json does not round trip
+type fidelityStruct struct {
+	ID     int64
+	Scores map[int]float64
+	Tags   []string
+	Nested *fidelityStruct
+}
+
+var fidelityStructData = fidelityStruct{
+	ID:     1<<62 + 1,
+	Scores: map[int]float64{1: 0.5, 2: 1.25},
+	Tags:   []string{"a", "b"},
+	Nested: &fidelityStruct{ID: 2, Tags: []string{"c"}},
+}
+
+func testRedisSerializerRoundTrip[V any](t *testing.T, serializer Serializer, value V) {
+	t.Helper()
+	driver := setupRedisDriver[V](t).WithSerializer(serializer).(*RedisDriver[V])
+
+	assert.NoError(t, driver.Set("key", value, time.Minute))
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	assert.NoError(t, driver.SetMany([]Many[V]{{Key: "many", Value: value, TTL: time.Minute}}))
+	many, err := driver.Many([]string{"key", "many"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]V{"key": value, "many": value}, many)
+
+	got, err = driver.RememberStale("stale", time.Minute, time.Minute, func() (V, error) {
+		return value, nil
+	})
+	assert.NoError(t, err)
+	got, err = driver.Get("stale")
+	assert.NoError(t, err)
+	assert.Equal(t, value, got)
+}
+
+func TestGobSerializer(t *testing.T) {
+	serializer := &GobSerializer{}
+	testRedisSerializerRoundTrip(t, serializer, testStructData)
+	testRedisSerializerRoundTrip(t, serializer, fidelityStructData)
+	testRedisSerializerRoundTrip(t, serializer, map[int]string{1: "a", 2: "b"})
+	testRedisSerializerRoundTrip(t, serializer, []float64{1.5, -2})
+	testRedisSerializerRoundTrip(t, serializer, int64(1<<62+1))
+	testRedisSerializerRoundTrip(t, serializer, uint8(255))
+
+	// gob keeps the offset of the time zone, not its name
+	local := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("", 8*3600))
+	testRedisSerializerRoundTrip(t, serializer, local)
+}
+
+func TestMsgpackSerializer(t *testing.T) {
+	serializer := &MsgpackSerializer{}
+	testRedisSerializerRoundTrip(t, serializer, fidelityStructData)
+	testRedisSerializerRoundTrip(t, serializer, map[int]string{1: "a", 2: "b"})
+	testRedisSerializerRoundTrip(t, serializer, []float64{1.5, -2})
+	testRedisSerializerRoundTrip(t, serializer, int64(1<<62+1))
+	testRedisSerializerRoundTrip(t, serializer, uint8(255))
+
+	data, err := serializer.Serialize(testStructData)
+	assert.NoError(t, err)
+	json, err := (&JSONSerializer{}).Serialize(testStructData)
+	assert.NoError(t, err)
+	assert.Less(t, len(data), len(json))
+}
+
+func TestRawSerializer(t *testing.T) {
+	serializer := &RawSerializer{}
+	testRedisSerializerRoundTrip(t, serializer, "plain string")
+	testRedisSerializerRoundTrip(t, serializer, []byte{0, 1, 2, 0xFF})
+
+	driver := setupRedisDriver[string](t).WithSerializer(serializer).(*RedisDriver[string])
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	raw, err := driver.redisClient.Get(driver.ctx, cacheKey(t, driver, "key")).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "value", raw, "strings are stored without quotes")
+
+	_, err = serializer.Serialize(1)
+	assert.EqualError(t, err, "raw serializer: unsupported type int")
+	var n int
+	assert.EqualError(t, serializer.UnSerialize([]byte("1"), &n), "raw serializer: unsupported type *int")
+}
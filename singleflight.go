@@ -0,0 +1,237 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/samber/lo"
+)
+
+var errLoaderPanicked = errors.New("cache loader panicked")
+
+// flightCall an in-flight or completed loader call
+type flightCall struct {
+	wg    sync.WaitGroup
+	val   any
+	found bool
+	err   error
+}
+
+// flightGroup coalesces concurrent loader calls for the same cache key,
+// so that only one goroutine runs the loader while the others wait for
+// and share its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// Do executes fn once for all concurrent callers of the same key, shared reports whether
+// the caller waited for the call of another caller.
+func (g *flightGroup) Do(key string, fn func() (any, error)) (val any, shared bool, err error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, true, c.err
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// waiters see errLoaderPanicked unless fn returns
+	c.err = errLoaderPanicked
+	defer g.finish(key, c)
+	c.val, c.err = fn()
+	c.found = c.err == nil
+	return c.val, false, c.err
+}
+
+// DoAsync executes fn in a new goroutine unless key is already being loaded,
+// and reports whether fn was started.
+func (g *flightGroup) DoAsync(key string, fn func() (any, error)) bool {
+	g.mu.Lock()
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return false
+	}
+	c := &flightCall{err: errLoaderPanicked}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		defer g.finish(key, c)
+		// nobody is there to handle the panic of a background load
+		defer func() {
+			_ = recover()
+		}()
+		c.val, c.err = fn()
+		c.found = c.err == nil
+	}()
+	return true
+}
+
+// DoMany executes fn with the keys which are not already being loaded by
+// another caller, waits for the keys which are, and returns the merged
+// result and the number of keys waited for. Keys omitted from the map
+// returned by fn are omitted from the result of every caller waiting on them.
+func (g *flightGroup) DoMany(keys []string, fn func(keys []string) (map[string]any, error)) (map[string]any, int, error) {
+	var (
+		owned   = make(map[string]*flightCall)
+		waiting = make(map[string]*flightCall)
+		ownKeys []string
+	)
+	g.mu.Lock()
+	for _, key := range keys {
+		if _, ok := owned[key]; ok {
+			continue
+		}
+		if c, ok := g.calls[key]; ok {
+			waiting[key] = c
+			continue
+		}
+		c := &flightCall{err: errLoaderPanicked}
+		c.wg.Add(1)
+		g.calls[key] = c
+		owned[key] = c
+		ownKeys = append(ownKeys, key)
+	}
+	g.mu.Unlock()
+
+	results := make(map[string]any, len(keys))
+	var err error
+	if len(ownKeys) > 0 {
+		func() {
+			defer func() {
+				for key, c := range owned {
+					g.finish(key, c)
+				}
+			}()
+			var values map[string]any
+			if values, err = fn(ownKeys); err != nil {
+				for _, c := range owned {
+					c.err = err
+				}
+				return
+			}
+			for key, c := range owned {
+				c.err = nil
+				c.val, c.found = values[key]
+			}
+			for key, value := range values {
+				results[key] = value
+			}
+		}()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	for key, c := range waiting {
+		c.wg.Wait()
+		if c.err != nil {
+			return nil, len(waiting), c.err
+		}
+		if c.found {
+			results[key] = c.val
+		}
+	}
+	return results, len(waiting), nil
+}
+
+func (g *flightGroup) finish(key string, c *flightCall) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	c.wg.Done()
+}
+
+// flightResult boxes a loaded value, so that a nil interface value is told
+// apart from a value loaded by a driver of another value type.
+type flightResult[V any] struct {
+	val V
+}
+
+// sharedLoadsKey the context key of the counter of the items a Remember* call got from the load of
+// another caller, see withSharedLoads
+type sharedLoadsKey struct{}
+
+// withSharedLoads a context recording into counter the items loaded by other callers
+func withSharedLoads(ctx context.Context, counter *int64) context.Context {
+	return context.WithValue(ctx, sharedLoadsKey{}, counter)
+}
+
+// recordSharedLoads add n items loaded by other callers to the counter of ctx, if any
+func recordSharedLoads(ctx context.Context, n int) {
+	if counter, ok := ctx.Value(sharedLoadsKey{}).(*int64); ok && n > 0 {
+		atomic.AddInt64(counter, int64(n))
+	}
+}
+
+// doFlight is the typed wrapper of flightGroup.Do, a caller sharing the load of another is recorded in ctx.
+func doFlight[V any](ctx context.Context, g *flightGroup, key string, fn func() (V, error)) (V, error) {
+	value, shared, err := g.Do(key, func() (any, error) {
+		val, err := fn()
+		return flightResult[V]{val: val}, err
+	})
+	result, ok := value.(flightResult[V])
+	if !ok && err == nil {
+		// the key is being loaded by a driver of another value type
+		return fn()
+	}
+	if shared {
+		recordSharedLoads(ctx, 1)
+	}
+	return result.val, err
+}
+
+// doFlightMany is the typed wrapper of flightGroup.DoMany, keys are
+// coalesced by their prefixed cache key.
+func doFlightMany[V any](d *baseDriver, keys []string, fn func(keys []string) (map[string]V, error)) (map[string]V, error) {
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	originKeys := make(map[string]string, len(keys))
+	for i, key := range keys {
+		originKeys[cacheKeys[i]] = key
+	}
+	values, shared, err := d.group.DoMany(cacheKeys, func(cacheKeys []string) (map[string]any, error) {
+		items, err := fn(lo.Map(cacheKeys, func(cacheKey string, _ int) string {
+			return originKeys[cacheKey]
+		}))
+		if err != nil {
+			return nil, err
+		}
+		results := make(map[string]any, len(items))
+		for key, item := range items {
+			cacheKey, err := d.getCacheKey(key)
+			if err != nil {
+				return nil, err
+			}
+			originKeys[cacheKey] = key
+			results[cacheKey] = flightResult[V]{val: item}
+		}
+		return results, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[string]V, len(values))
+	for cacheKey, value := range values {
+		item, ok := value.(flightResult[V])
+		if !ok {
+			// the key is being loaded by a driver of another value type
+			return fn(keys)
+		}
+		items[originKeys[cacheKey]] = item.val
+	}
+	recordSharedLoads(d.ctx, shared)
+	return items, nil
+}
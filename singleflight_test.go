@@ -0,0 +1,95 @@
+package cacheit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightGroupDoPanicReleasesWaiters(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		defer func() {
+			_ = recover()
+		}()
+		_, _, _ = g.Do("key", func() (any, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	done := make(chan error)
+	go func() {
+		_, _, err := g.Do("key", func() (any, error) {
+			return "unexpected", nil
+		})
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, errLoaderPanicked)
+	case <-time.After(time.Second):
+		t.Fatal("waiter was not released")
+	}
+}
+
+func TestFlightGroupDoManyOmittedKeys(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]map[string]any, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = g.DoMany([]string{"found", "missing"}, func(keys []string) (map[string]any, error) {
+				<-release
+				return map[string]any{"found": 1}, nil
+			})
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, result := range results {
+		assert.Equal(t, map[string]any{"found": 1}, result)
+	}
+}
+
+func TestDoFlightNilInterfaceValue(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := doFlight(context.Background(), g, "key", func() (error, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			assert.Nil(t, value)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,145 @@
+package cacheit
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stats snapshot of the counters of a registered driver
+type Stats struct {
+	// Hits items found in the cache, including the negative cached items
+	Hits uint64
+	// Misses items not found in the cache
+	Misses uint64
+	// Sets items written to the cache
+	Sets uint64
+	// Deletes items removed from the cache
+	Deletes uint64
+	// Errors failed cache operations, cache misses and existed items are not errors
+	Errors uint64
+	// LoaderCalls callbacks executed by Remember*
+	LoaderCalls uint64
+	// LoaderLatency total time spent in the callbacks of Remember*
+	LoaderLatency time.Duration
+	// BytesSerialized bytes produced by the serializer of the redis driver
+	BytesSerialized uint64
+}
+
+// HitRatio hits over lookups, 0 without lookups
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// AvgLoaderLatency average time spent in a callback of Remember*, 0 without calls
+func (s Stats) AvgLoaderLatency() time.Duration {
+	if s.LoaderCalls == 0 {
+		return 0
+	}
+	return s.LoaderLatency / time.Duration(s.LoaderCalls)
+}
+
+// GetStats get a snapshot of the counters of the driver registered as driverName
+func GetStats(driverName string) (Stats, error) {
+	value, ok := registerDrivers.Load(driverName)
+	if !ok {
+		return Stats{}, fmt.Errorf("cached driver: %s not registered", driverName)
+	}
+	return value.(*baseDriver).stats.snapshot(), nil
+}
+
+// ResetStats reset the counters of the driver registered as driverName
+func ResetStats(driverName string) error {
+	value, ok := registerDrivers.Load(driverName)
+	if !ok {
+		return fmt.Errorf("cached driver: %s not registered", driverName)
+	}
+	value.(*baseDriver).stats.reset()
+	return nil
+}
+
+// driverStats counters of a registered driver, shared by every Use of the driver and updated atomically
+type driverStats struct {
+	hits            uint64
+	misses          uint64
+	sets            uint64
+	deletes         uint64
+	errors          uint64
+	loaderCalls     uint64
+	loaderLatency   uint64
+	bytesSerialized uint64
+}
+
+func (s *driverStats) snapshot() Stats {
+	return Stats{
+		Hits:            atomic.LoadUint64(&s.hits),
+		Misses:          atomic.LoadUint64(&s.misses),
+		Sets:            atomic.LoadUint64(&s.sets),
+		Deletes:         atomic.LoadUint64(&s.deletes),
+		Errors:          atomic.LoadUint64(&s.errors),
+		LoaderCalls:     atomic.LoadUint64(&s.loaderCalls),
+		LoaderLatency:   time.Duration(atomic.LoadUint64(&s.loaderLatency)),
+		BytesSerialized: atomic.LoadUint64(&s.bytesSerialized),
+	}
+}
+
+func (s *driverStats) reset() {
+	for _, counter := range []*uint64{&s.hits, &s.misses, &s.sets, &s.deletes, &s.errors, &s.loaderCalls, &s.loaderLatency, &s.bytesSerialized} {
+		atomic.StoreUint64(counter, 0)
+	}
+}
+
+// lookup record the result of a single key read
+func (s *driverStats) lookup(err error) {
+	switch {
+	case err == nil, errors.Is(err, ErrNotFound):
+		atomic.AddUint64(&s.hits, 1)
+	case errors.Is(err, ErrCacheMiss):
+		atomic.AddUint64(&s.misses, 1)
+	default:
+		atomic.AddUint64(&s.errors, 1)
+	}
+}
+
+// lookupMany record the hits of a read of keys keys
+func (s *driverStats) lookupMany(keys, hits int) {
+	atomic.AddUint64(&s.hits, uint64(hits))
+	atomic.AddUint64(&s.misses, uint64(keys-hits))
+}
+
+// set record n written items
+func (s *driverStats) set(n int) {
+	atomic.AddUint64(&s.sets, uint64(n))
+}
+
+// del record n removed items
+func (s *driverStats) del(n int) {
+	atomic.AddUint64(&s.deletes, uint64(n))
+}
+
+// fail record err unless it is nil, a cache miss or an existed item
+func (s *driverStats) fail(err error) error {
+	if err != nil && !errors.Is(err, ErrCacheMiss) && !errors.Is(err, ErrCacheExisted) {
+		atomic.AddUint64(&s.errors, 1)
+	}
+	return err
+}
+
+// serialized record the size of a serialized value
+func (s *driverStats) serialized(data []byte) {
+	atomic.AddUint64(&s.bytesSerialized, uint64(len(data)))
+}
+
+// load execute the callback of Remember* and record its latency
+func load[T any](s *driverStats, callback func() (T, error)) (T, error) {
+	start := time.Now()
+	defer func() {
+		atomic.AddUint64(&s.loaderCalls, 1)
+		atomic.AddUint64(&s.loaderLatency, uint64(time.Since(start)))
+	}()
+	return callback()
+}
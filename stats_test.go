@@ -0,0 +1,108 @@
+package cacheit
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStats(t *testing.T, driver Driver[string]) {
+	stats := driver.(baseProvider).base().stats
+	stats.reset()
+
+	assert.NoError(t, driver.Set("a", "1", time.Minute))
+	assert.NoError(t, driver.SetMany([]Many[string]{{Key: "b", Value: "2", TTL: time.Minute}, {Key: "c", Value: "3", TTL: time.Minute}}))
+	_, err := driver.Get("a")
+	assert.NoError(t, err)
+	_, err = driver.Get("missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = driver.Many([]string{"b", "c", "missing"})
+	assert.NoError(t, err)
+	assert.NoError(t, driver.Forget("a"))
+	assert.NoError(t, driver.DelMany([]string{"b", "c"}))
+
+	_, err = driver.Remember("loaded", time.Minute, func() (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		return "v", nil
+	}, false)
+	assert.NoError(t, err)
+	_, err = driver.Remember("failed", time.Minute, func() (string, error) {
+		return "", errors.New("source down")
+	}, false)
+	assert.Error(t, err)
+
+	snapshot := stats.snapshot()
+	assert.Equal(t, uint64(3), snapshot.Hits)
+	assert.Equal(t, uint64(4), snapshot.Misses)
+	assert.Equal(t, uint64(4), snapshot.Sets)
+	assert.Equal(t, uint64(3), snapshot.Deletes)
+	assert.Equal(t, uint64(0), snapshot.Errors)
+	assert.Equal(t, uint64(2), snapshot.LoaderCalls)
+	assert.GreaterOrEqual(t, snapshot.LoaderLatency, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, snapshot.AvgLoaderLatency(), 5*time.Millisecond)
+	assert.InDelta(t, 3.0/7.0, snapshot.HitRatio(), 0.001)
+
+	stats.reset()
+	assert.Equal(t, Stats{}, stats.snapshot())
+	assert.NoError(t, driver.Flush())
+}
+
+func TestRedisStats(t *testing.T) {
+	driver := setupRedisDriver[string](t)
+	testStats(t, driver)
+
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	assert.Equal(t, uint64(len(`"value"`)), driver.stats.snapshot().BytesSerialized)
+}
+
+func TestGoCacheStats(t *testing.T) {
+	driver := setupGoCacheDriver[string](t)
+	testStats(t, driver)
+
+	driver.memCache.Set(cacheKey(t, driver, "mismatch"), 1, time.Minute)
+	_, err := driver.Get("mismatch")
+	assert.Error(t, err)
+	assert.Equal(t, uint64(1), driver.stats.snapshot().Errors)
+}
+
+func TestTieredStats(t *testing.T) {
+	testStats(t, setupTieredDriver[string](t, time.Minute))
+}
+
+func TestStatsByDriverName(t *testing.T) {
+	driverName := nextDriverName("stats_test")
+	require.NoError(t, RegisterGoCacheDriver(driverName, gocache.New(time.Minute, time.Minute), "stats"))
+
+	// every Use of the driver shares the counters
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			driver, err := Use[string](driverName)
+			assert.NoError(t, err)
+			for j := 0; j < 100; j++ {
+				_, _ = driver.Get("key")
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, err := GetStats(driverName)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), stats.Misses)
+
+	assert.NoError(t, ResetStats(driverName))
+	stats, err = GetStats(driverName)
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{}, stats)
+
+	_, err = GetStats("not_registered")
+	assert.Error(t, err)
+	assert.Error(t, ResetStats("not_registered"))
+}
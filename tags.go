@@ -0,0 +1,384 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+)
+
+// baseProvider implemented by every driver through the embedded baseDriver
+type baseProvider interface {
+	base() *baseDriver
+}
+
+func (d *baseDriver) base() *baseDriver {
+	return d
+}
+
+// tagIndexKey cache key of the index of the keys tagged with tag
+func (d *baseDriver) tagIndexKey(tag string) (string, error) {
+	return d.getCacheKey(internalKeyPrefix + "tag:" + tag + ":entries")
+}
+
+// tagIndexKeys cache keys of the indexes of tags
+func (d *baseDriver) tagIndexKeys(tags []string) ([]string, error) {
+	return d.getCacheKeys(lo.Map(tags, func(tag string, _ int) string {
+		return internalKeyPrefix + "tag:" + tag + ":entries"
+	}))
+}
+
+// tagExpiry the unix milliseconds at which an item written with ttl expires, 0 if it never expires
+func tagExpiry(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).UnixMilli()
+}
+
+// addTagged record keys under each tag of the index
+func (d *baseDriver) addTagged(tags []string, keys []string, ttl time.Duration) error {
+	if len(tags) == 0 || len(keys) == 0 {
+		return nil
+	}
+	if d.redisClient == nil {
+		for _, tag := range tags {
+			d.memTagSet(tag).add(keys, tagExpiry(d.maxJitter(ttl)))
+		}
+		return nil
+	}
+	// expired members are scored in the past and pruned, members without expiration are scored -1
+	score := float64(-1)
+	if expiry := tagExpiry(d.maxJitter(ttl)); expiry > 0 {
+		score = float64(expiry)
+	}
+	members := lo.Map(keys, func(key string, _ int) *redis.Z {
+		return &redis.Z{Score: score, Member: key}
+	})
+	indexKeys, err := d.tagIndexKeys(tags)
+	if err != nil {
+		return err
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	_, err = d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		for _, indexKey := range indexKeys {
+			pipe.ZRemRangeByScore(d.ctx, indexKey, "0", now)
+			pipe.ZAdd(d.ctx, indexKey, members...)
+		}
+		return nil
+	})
+	return err
+}
+
+// taggedKeys the live keys recorded under tags
+func (d *baseDriver) taggedKeys(tags []string) ([]string, error) {
+	var keys []string
+	if d.redisClient == nil {
+		for _, tag := range tags {
+			keys = append(keys, d.memTagSet(tag).keys()...)
+		}
+		return lo.Uniq(keys), nil
+	}
+	indexKeys, err := d.tagIndexKeys(tags)
+	if err != nil {
+		return nil, err
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	cmds := make([]*redis.StringSliceCmd, 0, len(tags))
+	_, err = d.redisClient.Pipelined(d.ctx, func(pipe redis.Pipeliner) error {
+		for _, indexKey := range indexKeys {
+			pipe.ZRemRangeByScore(d.ctx, indexKey, "0", now)
+			cmds = append(cmds, pipe.ZRange(d.ctx, indexKey, 0, -1))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range cmds {
+		keys = append(keys, cmd.Val()...)
+	}
+	return lo.Uniq(keys), nil
+}
+
+// deleteTagIndexes remove the indexes of tags
+func (d *baseDriver) deleteTagIndexes(tags []string) error {
+	indexKeys, err := d.tagIndexKeys(tags)
+	if err != nil {
+		return err
+	}
+	if d.redisClient == nil {
+		for _, indexKey := range indexKeys {
+			d.memCache.Delete(indexKey)
+		}
+		return nil
+	}
+	return d.delKeys(d.ctx, d.redisClient, indexKeys)
+}
+
+// memTagSet keys tagged with a tag of a go-cache driver, stored in the go-cache itself
+type memTagSet struct {
+	mu      sync.Mutex
+	members map[string]int64
+}
+
+// memTagSet get or create the index of tag, the version of the memory namespace of go-cache drivers is
+// always known
+func (d *baseDriver) memTagSet(tag string) *memTagSet {
+	indexKey, _ := d.tagIndexKey(tag)
+	for {
+		value, found := d.memCache.Get(indexKey)
+		if set, ok := value.(*memTagSet); ok {
+			return set
+		}
+		set := &memTagSet{members: make(map[string]int64)}
+		if found {
+			d.memCache.Set(indexKey, set, gocache.NoExpiration)
+			return set
+		}
+		// retry if another goroutine created the index first
+		if err := d.memCache.Add(indexKey, set, gocache.NoExpiration); err == nil {
+			return set
+		}
+	}
+}
+
+func (s *memTagSet) add(keys []string, expiry int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	for _, key := range keys {
+		s.members[key] = expiry
+	}
+}
+
+func (s *memTagSet) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	return lo.Keys(s.members)
+}
+
+// prune remove the expired members, s.mu must be held
+func (s *memTagSet) prune() {
+	now := time.Now().UnixMilli()
+	for key, expiry := range s.members {
+		if expiry > 0 && expiry < now {
+			delete(s.members, key)
+		}
+	}
+}
+
+// taggedDriver a view of a driver whose writes are recorded under tags, Flush removes the tagged items only
+type taggedDriver[V any] struct {
+	Driver[V]
+	tags []string
+}
+
+func newTaggedDriver[V any](driver Driver[V], tags []string) Driver[V] {
+	return &taggedDriver[V]{
+		Driver: driver,
+		tags:   lo.Uniq(tags),
+	}
+}
+
+func (t *taggedDriver[V]) base() *baseDriver {
+	return t.Driver.(baseProvider).base()
+}
+
+func (t *taggedDriver[V]) Add(key string, value V, ttl time.Duration) error {
+	if err := t.Driver.Add(key, value, ttl); err != nil {
+		return err
+	}
+	return t.base().addTagged(t.tags, []string{key}, ttl)
+}
+
+func (t *taggedDriver[V]) Set(key string, value V, ttl time.Duration) error {
+	if err := t.Driver.Set(key, value, ttl); err != nil {
+		return err
+	}
+	return t.base().addTagged(t.tags, []string{key}, ttl)
+}
+
+func (t *taggedDriver[V]) SetMany(many []Many[V]) error {
+	// the keys of the failed chunks are tagged too, they are skipped by Flush
+	err := t.Driver.SetMany(many)
+	if err != nil && !errors.As(err, new(*ChunkError)) {
+		return err
+	}
+	for _, item := range many {
+		if tagErr := t.base().addTagged(t.tags, []string{item.Key}, item.TTL); tagErr != nil {
+			return tagErr
+		}
+	}
+	return err
+}
+
+func (t *taggedDriver[V]) Forever(key string, value V) error {
+	if err := t.Driver.Forever(key, value); err != nil {
+		return err
+	}
+	return t.base().addTagged(t.tags, []string{key}, 0)
+}
+
+func (t *taggedDriver[V]) Flush() error {
+	d := t.base()
+	keys, err := d.taggedKeys(t.tags)
+	if err != nil {
+		return err
+	}
+	if err = t.Driver.DelMany(keys); err != nil {
+		return err
+	}
+	return d.deleteTagIndexes(t.tags)
+}
+
+func (t *taggedDriver[V]) SetNumber(key string, value V, ttl time.Duration) error {
+	if err := t.Driver.SetNumber(key, value, ttl); err != nil {
+		return err
+	}
+	return t.base().addTagged(t.tags, []string{key}, ttl)
+}
+
+func (t *taggedDriver[V]) Increment(key string, n V) (V, error) {
+	ret, err := t.Driver.Increment(key, n)
+	if err != nil {
+		return ret, err
+	}
+	return ret, t.base().addTagged(t.tags, []string{key}, 0)
+}
+
+func (t *taggedDriver[V]) Decrement(key string, n V) (V, error) {
+	ret, err := t.Driver.Decrement(key, n)
+	if err != nil {
+		return ret, err
+	}
+	return ret, t.base().addTagged(t.tags, []string{key}, 0)
+}
+
+func (t *taggedDriver[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (V, error) {
+	return t.Driver.Remember(key, ttl, func() (V, error) {
+		result, err := callback()
+		if err != nil {
+			return result, err
+		}
+		return result, t.base().addTagged(t.tags, []string{key}, ttl)
+	}, force)
+}
+
+func (t *taggedDriver[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
+	return t.Remember(key, 0, callback, force)
+}
+
+func (t *taggedDriver[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return t.Driver.RememberStale(key, freshTTL, staleTTL, func() (V, error) {
+		result, err := callback()
+		if err != nil {
+			return result, err
+		}
+		return result, t.base().addTagged(t.tags, []string{key}, freshTTL+staleTTL)
+	})
+}
+
+func (t *taggedDriver[V]) RememberMany(keys []string, ttl time.Duration, callback func(notHitKeys []string) (map[string]V, error), force bool) (map[string]V, error) {
+	return t.Driver.RememberMany(keys, ttl, func(notHitKeys []string) (map[string]V, error) {
+		items, err := callback(notHitKeys)
+		if err != nil {
+			return nil, err
+		}
+		return items, t.base().addTagged(t.tags, lo.Keys(items), ttl)
+	}, force)
+}
+
+// retag record the ttl set on keys by an operation returning err, the missing keys of the batch
+// operations are recorded too, they are skipped by Flush
+func (t *taggedDriver[V]) retag(keys []string, ttl time.Duration, err error) error {
+	if err != nil && (len(keys) == 1 || !errors.Is(err, ErrCacheMiss)) {
+		return err
+	}
+	if tagErr := t.base().addTagged(t.tags, keys, ttl); tagErr != nil {
+		return tagErr
+	}
+	return err
+}
+
+func (t *taggedDriver[V]) Touch(key string, ttl time.Duration) error {
+	return t.retag([]string{key}, ttl, t.Driver.Touch(key, ttl))
+}
+
+func (t *taggedDriver[V]) TouchMany(keys []string, ttl time.Duration) error {
+	return t.retag(keys, ttl, t.Driver.TouchMany(keys, ttl))
+}
+
+func (t *taggedDriver[V]) ExpireAt(key string, at time.Time) error {
+	err := t.Driver.ExpireAt(key, at)
+	if ttl := time.Until(at); ttl > 0 {
+		return t.retag([]string{key}, ttl, err)
+	}
+	return err
+}
+
+func (t *taggedDriver[V]) ExpireAtMany(keys []string, at time.Time) error {
+	err := t.Driver.ExpireAtMany(keys, at)
+	if ttl := time.Until(at); ttl > 0 {
+		return t.retag(keys, ttl, err)
+	}
+	return err
+}
+
+func (t *taggedDriver[V]) Persist(key string) error {
+	return t.retag([]string{key}, 0, t.Driver.Persist(key))
+}
+
+func (t *taggedDriver[V]) PersistMany(keys []string) error {
+	return t.retag(keys, 0, t.Driver.PersistMany(keys))
+}
+
+func (t *taggedDriver[V]) GetAndTouch(key string, ttl time.Duration) (V, error) {
+	result, err := t.Driver.GetAndTouch(key, ttl)
+	return result, t.retag([]string{key}, ttl, err)
+}
+
+func (t *taggedDriver[V]) GetSet(key string, value V, ttl time.Duration) (V, error) {
+	old, err := t.Driver.GetSet(key, value, ttl)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		return old, err
+	}
+	if tagErr := t.base().addTagged(t.tags, []string{key}, ttl); tagErr != nil {
+		return old, tagErr
+	}
+	return old, err
+}
+
+func (t *taggedDriver[V]) CompareAndSwap(key string, old, value V, ttl time.Duration) (bool, error) {
+	swapped, err := t.Driver.CompareAndSwap(key, old, value, ttl)
+	if err != nil || !swapped {
+		return swapped, err
+	}
+	return true, t.base().addTagged(t.tags, []string{key}, ttl)
+}
+
+func (t *taggedDriver[V]) Update(key string, ttl time.Duration, fn func(old V, found bool) (V, error)) (V, error) {
+	result, err := t.Driver.Update(key, ttl, fn)
+	if err != nil {
+		return result, err
+	}
+	return result, t.base().addTagged(t.tags, []string{key}, ttl)
+}
+
+func (t *taggedDriver[V]) Tags(tags ...string) Driver[V] {
+	return newTaggedDriver(t.Driver, append(append([]string{}, t.tags...), tags...))
+}
+
+func (t *taggedDriver[V]) WithCtx(ctx context.Context) Driver[V] {
+	return newTaggedDriver(t.Driver.WithCtx(ctx), t.tags)
+}
+
+func (t *taggedDriver[V]) WithSerializer(serializer Serializer) Driver[V] {
+	return newTaggedDriver(t.Driver.WithSerializer(serializer), t.tags)
+}
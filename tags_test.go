@@ -0,0 +1,130 @@
+package cacheit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTags(t *testing.T, driver Driver[string]) {
+	t.Run("tag flush", func(t *testing.T) {
+		user := driver.Tags("user:42")
+		assert.NoError(t, user.Set("user:42:profile", "profile", time.Minute))
+		assert.NoError(t, driver.Tags("user:42", "orders").Set("user:42:orders", "orders", time.Minute))
+		assert.NoError(t, driver.Tags("orders").Forever("user:43:orders", "orders"))
+		assert.NoError(t, driver.Set("untagged", "value", time.Minute))
+
+		got, err := user.Get("user:42:profile")
+		assert.NoError(t, err)
+		assert.Equal(t, "profile", got)
+
+		assert.NoError(t, user.Flush())
+
+		got2, err := driver.Many([]string{"user:42:profile", "user:42:orders", "user:43:orders", "untagged"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"user:43:orders": "orders", "untagged": "value"}, got2)
+
+		assert.NoError(t, driver.Tags("orders").Flush())
+		has, err := driver.Has("user:43:orders")
+		assert.NoError(t, err)
+		assert.False(t, has)
+		has, err = driver.Has("untagged")
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.NoError(t, driver.Flush())
+	})
+	t.Run("tag remember", func(t *testing.T) {
+		tagged := driver.Tags("remember")
+		got, err := tagged.Remember("remember:1", time.Minute, func() (string, error) {
+			return "one", nil
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "one", got)
+		many, err := tagged.RememberMany([]string{"remember:2", "remember:3"}, time.Minute, func(notHitKeys []string) (map[string]string, error) {
+			ret := make(map[string]string)
+			for _, key := range notHitKeys {
+				ret[key] = key
+			}
+			return ret, nil
+		}, false)
+		assert.NoError(t, err)
+		assert.Len(t, many, 2)
+
+		assert.NoError(t, tagged.Flush())
+		got2, err := driver.Many([]string{"remember:1", "remember:2", "remember:3"})
+		assert.NoError(t, err)
+		assert.Empty(t, got2)
+	})
+	t.Run("tag index prunes expired members", func(t *testing.T) {
+		tagged := driver.Tags("stale")
+		assert.NoError(t, tagged.Set("stale:short", "short", 50*time.Millisecond))
+		assert.NoError(t, tagged.Set("stale:long", "long", time.Minute))
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, tagged.Set("stale:other", "other", time.Minute))
+
+		keys, err := driver.(baseProvider).base().taggedKeys([]string{"stale"})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"stale:long", "stale:other"}, keys)
+		assert.NoError(t, driver.Flush())
+	})
+}
+
+func TestRedisTags(t *testing.T) {
+	driver := setupRedisDriver[string](t)
+	testTags(t, driver)
+
+	assert.NoError(t, driver.Tags("index").Set("key", "value", time.Minute))
+	n, err := driver.redisClient.ZCard(driver.ctx, "cache_prefix:\x00cacheit:tag:index:entries").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestGoCacheTags(t *testing.T) {
+	testTags(t, setupGoCacheDriver[string](t))
+}
+
+func TestTieredTags(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close())
+	})
+
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	driverName := nextDriverName("tiered_tags")
+	require.NoError(t, RegisterTieredDriver(driverName, memCache, client, "cache_prefix", time.Minute))
+	driver, err := Use[string](driverName)
+	require.NoError(t, err)
+	testTags(t, driver)
+
+	// tagged keys read into L1 are dropped by a tag flush
+	assert.NoError(t, driver.Tags("l1").Set("l1", "value", time.Minute))
+	_, err = driver.Get("l1")
+	assert.NoError(t, err)
+	assert.NoError(t, driver.Tags("l1").Flush())
+	_, found := memCache.Get("cache_prefix:l1")
+	assert.False(t, found)
+}
+
+func TestTaggedTouchUpdatesIndex(t *testing.T) {
+	driver := setupGoCacheDriver[string](t)
+	tagged := driver.Tags("tag")
+	assert.NoError(t, tagged.Set("key", "value", time.Minute))
+	assert.NotZero(t, driver.memTagSet("tag").members["key"])
+
+	assert.NoError(t, tagged.Persist("key"))
+	assert.Zero(t, driver.memTagSet("tag").members["key"], "persisted items never leave the index")
+
+	assert.ErrorIs(t, tagged.Touch("missing", time.Minute), ErrCacheMiss)
+	assert.NotContains(t, driver.memTagSet("tag").members, "missing")
+
+	assert.NoError(t, tagged.ExpireAt("key", time.Now().Add(time.Hour)))
+	assert.Greater(t, driver.memTagSet("tag").members["key"], time.Now().Add(59*time.Minute).UnixMilli())
+}
@@ -0,0 +1,391 @@
+package cacheit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// TieredDriver go-cache (L1) in front of go-redis (L2) driver implemented
+type TieredDriver[V any] struct {
+	baseDriver
+}
+
+// local the go-cache tier
+func (d *TieredDriver[V]) local() *GoCacheDriver[V] {
+	return &GoCacheDriver[V]{d.baseDriver}
+}
+
+// remote the redis tier
+func (d *TieredDriver[V]) remote() *RedisDriver[V] {
+	return &RedisDriver[V]{d.baseDriver}
+}
+
+// invalidate drop the local copies of keys, the redis tier publishes the invalidation to other processes
+func (d *TieredDriver[V]) invalidate(keys ...string) {
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		// nothing is kept locally before the version of the namespace is first read
+		return
+	}
+	for _, cacheKey := range cacheKeys {
+		d.memCache.Delete(cacheKey)
+	}
+}
+
+func (d *TieredDriver[V]) Add(key string, value V, t time.Duration) error {
+	defer d.invalidate(key)
+	return d.remote().Add(key, value, t)
+}
+
+func (d *TieredDriver[V]) Set(key string, value V, t time.Duration) error {
+	defer d.invalidate(key)
+	return d.remote().Set(key, value, t)
+}
+
+func (d *TieredDriver[V]) SetMany(many []Many[V]) error {
+	defer d.invalidate(lo.Map(many, func(item Many[V], _ int) string {
+		return item.Key
+	})...)
+	return d.remote().SetMany(many)
+}
+
+func (d *TieredDriver[V]) Forever(key string, value V) error {
+	defer d.invalidate(key)
+	return d.remote().Forever(key, value)
+}
+
+func (d *TieredDriver[V]) Forget(key string) error {
+	defer d.invalidate(key)
+	return d.remote().Forget(key)
+}
+
+func (d *TieredDriver[V]) Del(key string) error {
+	return d.Forget(key)
+}
+
+func (d *TieredDriver[V]) Flush() error {
+	defer flushMemCache(d.memCache, d.prefix)
+	return d.remote().Flush()
+}
+
+func (d *TieredDriver[V]) Get(key string) (V, error) {
+	result, _, err := d.getEntry(key)
+	d.stats.lookup(err)
+	return result, err
+}
+
+func (d *TieredDriver[V]) getEntry(key string) (V, entryMeta, error) {
+	if result, meta, err := d.local().getEntry(key); err == nil || errors.Is(err, ErrNotFound) {
+		return result, meta, err
+	}
+	result, meta, ttl, err := d.remote().getEntryTTL(key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		d.backfillTombstones([]string{key})
+	case err == nil:
+		d.backfill(key, result, meta, ttl)
+	}
+	return result, meta, err
+}
+
+func (d *TieredDriver[V]) setEntry(key string, value V, meta entryMeta, ttl time.Duration) error {
+	defer d.invalidate(key)
+	return d.remote().setEntry(key, value, meta, ttl)
+}
+
+func (d *TieredDriver[V]) setTombstones(keys []string) error {
+	defer d.invalidate(keys...)
+	return d.remote().setTombstones(keys)
+}
+
+// backfill keep a local copy of a value read from redis for at most localTTL and never
+// longer than remoteTTL, the remaining ttl of the item in redis
+func (d *TieredDriver[V]) backfill(key string, value V, meta entryMeta, remoteTTL time.Duration) {
+	ttl := d.localTTL
+	if remoteTTL != NoExpirationTTL {
+		if remoteTTL <= 0 {
+			// the ttl of the item is unknown or it has just expired
+			return
+		}
+		if remoteTTL < ttl {
+			ttl = remoteTTL
+		}
+	}
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return
+	}
+	stored, err := d.local().encode(value, meta)
+	if err != nil {
+		return
+	}
+	d.memCache.Set(cacheKey, stored, ttl)
+}
+
+// backfillTombstones keep a local copy of the tombstones read from redis for at most localTTL
+func (d *TieredDriver[V]) backfillTombstones(keys []string) {
+	ttl := d.localTTL
+	if d.negativeTTL > 0 && d.negativeTTL < ttl {
+		ttl = d.negativeTTL
+	}
+	cacheKeys, err := d.getCacheKeys(keys)
+	if err != nil {
+		return
+	}
+	for _, cacheKey := range cacheKeys {
+		d.memCache.Set(cacheKey, tombstone{}, ttl)
+	}
+}
+
+func (d *TieredDriver[V]) Has(key string) (bool, error) {
+	if found, _ := d.local().Has(key); found {
+		return true, nil
+	}
+	return d.remote().Has(key)
+}
+
+func (d *TieredDriver[V]) Many(keys []string) (map[string]V, error) {
+	results, notFoundKeys, err := d.many(keys)
+	if err != nil {
+		return results, d.stats.fail(err)
+	}
+	d.stats.lookupMany(len(keys), len(results)+len(notFoundKeys))
+	return results, nil
+}
+
+// many Retrieve multiple items and the keys of the negative cached items from the cache, the items of
+// the redis chunks which succeeded are returned with a *ChunkError.
+func (d *TieredDriver[V]) many(keys []string) (map[string]V, []string, error) {
+	results, notFoundKeys, err := d.local().many(keys)
+	if err != nil {
+		results, notFoundKeys = make(map[string]V), nil
+	}
+	notHitKeys := lo.Without(keys, append(lo.Keys(results), notFoundKeys...)...)
+	if len(notHitKeys) == 0 {
+		return results, notFoundKeys, nil
+	}
+	ttls := make(map[string]time.Duration, len(notHitKeys))
+	remoteResults, remoteNotFoundKeys, err := d.remote().manyTTL(notHitKeys, ttls)
+	if err != nil && !errors.As(err, new(*ChunkError)) {
+		return nil, nil, err
+	}
+	for key, value := range remoteResults {
+		d.backfill(key, value, entryMeta{}, ttls[key])
+	}
+	d.backfillTombstones(remoteNotFoundKeys)
+	return lo.Assign(results, remoteResults), append(notFoundKeys, remoteNotFoundKeys...), err
+}
+
+func (d *TieredDriver[V]) DelMany(keys []string) error {
+	defer d.invalidate(keys...)
+	return d.remote().DelMany(keys)
+}
+
+func (d *TieredDriver[V]) ForgetMany(keys []string) error {
+	return d.DelMany(keys)
+}
+
+func (d *TieredDriver[V]) SetNumber(key string, value V, t time.Duration) error {
+	defer d.invalidate(key)
+	return d.remote().SetNumber(key, value, t)
+}
+
+func (d *TieredDriver[V]) Increment(key string, n V) (V, error) {
+	defer d.invalidate(key)
+	return d.remote().Increment(key, n)
+}
+
+func (d *TieredDriver[V]) Decrement(key string, n V) (V, error) {
+	defer d.invalidate(key)
+	return d.remote().Decrement(key, n)
+}
+
+func (d *TieredDriver[V]) Remember(key string, ttl time.Duration, callback func() (V, error), force bool) (result V, err error) {
+	if d.xfetch != nil {
+		return rememberEarly[V](d, key, ttl, callback, force)
+	}
+	if !force {
+		if result, err = d.Get(key); err == nil || errors.Is(err, ErrNotFound) {
+			return
+		}
+	}
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		return result, d.stats.fail(err)
+	}
+	return doFlight(d.ctx, d.group, cacheKey, func() (V, error) {
+		result, err := load(d.stats, callback)
+		if errors.Is(err, ErrNotFound) {
+			if err := d.setTombstones([]string{key}); err != nil {
+				return result, err
+			}
+		}
+		if err != nil {
+			return result, err
+		}
+		return result, d.Set(key, result, ttl)
+	})
+}
+
+func (d *TieredDriver[V]) RememberForever(key string, callback func() (V, error), force bool) (V, error) {
+	return d.Remember(key, 0, callback, force)
+}
+
+func (d *TieredDriver[V]) RememberMany(keys []string, ttl time.Duration, callback func(notHitKeys []string) (map[string]V, error), force bool) (map[string]V, error) {
+	var (
+		notHitKeys []string
+		err        error
+	)
+	many := make(map[string]V)
+	if !force {
+		var notFoundKeys []string
+		many, notFoundKeys, err = d.many(keys)
+		// the keys of the failed chunks are loaded like the missing ones
+		if err != nil && !errors.As(err, new(*ChunkError)) {
+			return nil, d.stats.fail(err)
+		}
+		_ = d.stats.fail(err)
+		d.stats.lookupMany(len(keys), len(many)+len(notFoundKeys))
+		notHitKeys = lo.Without(keys, append(lo.Keys(many), notFoundKeys...)...)
+		if len(notHitKeys) == 0 {
+			return many, nil
+		}
+	} else {
+		notHitKeys = keys
+	}
+	notCacheItems, err := doFlightMany(&d.baseDriver, notHitKeys, func(notHitKeys []string) (map[string]V, error) {
+		notCacheItems, err := load(d.stats, func() (map[string]V, error) {
+			return callback(notHitKeys)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var needCacheItems []Many[V]
+		for s, v := range notCacheItems {
+			needCacheItems = append(needCacheItems, Many[V]{
+				Key:   s,
+				Value: v,
+				TTL:   ttl,
+			})
+		}
+		if err = d.SetMany(needCacheItems); err != nil {
+			return nil, err
+		}
+		if err = d.setTombstones(lo.Without(notHitKeys, lo.Keys(notCacheItems)...)); err != nil {
+			return nil, err
+		}
+		return notCacheItems, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lo.Assign(many, notCacheItems), nil
+}
+
+func (d *TieredDriver[V]) RememberStale(key string, freshTTL, staleTTL time.Duration, callback func() (V, error)) (V, error) {
+	return rememberStale[V](d, func() entryStore[V] {
+		detached := &TieredDriver[V]{d.baseDriver}
+		detached.ctx = detachContext(d.ctx)
+		return detached
+	}, key, freshTTL, staleTTL, callback)
+}
+
+// TTL Get cache ttl, redis is authoritative
+func (d *TieredDriver[V]) TTL(key string) (time.Duration, error) {
+	return d.remote().TTL(key)
+}
+
+func (d *TieredDriver[V]) Touch(key string, ttl time.Duration) error {
+	defer d.invalidate(key)
+	return d.remote().Touch(key, ttl)
+}
+
+func (d *TieredDriver[V]) TouchMany(keys []string, ttl time.Duration) error {
+	defer d.invalidate(keys...)
+	return d.remote().TouchMany(keys, ttl)
+}
+
+func (d *TieredDriver[V]) ExpireAt(key string, at time.Time) error {
+	defer d.invalidate(key)
+	return d.remote().ExpireAt(key, at)
+}
+
+func (d *TieredDriver[V]) ExpireAtMany(keys []string, at time.Time) error {
+	defer d.invalidate(keys...)
+	return d.remote().ExpireAtMany(keys, at)
+}
+
+func (d *TieredDriver[V]) Persist(key string) error {
+	defer d.invalidate(key)
+	return d.remote().Persist(key)
+}
+
+func (d *TieredDriver[V]) PersistMany(keys []string) error {
+	defer d.invalidate(keys...)
+	return d.remote().PersistMany(keys)
+}
+
+// GetAndTouch always reads redis, whose ttl is the one slid, and keeps a local copy of the item
+func (d *TieredDriver[V]) GetAndTouch(key string, ttl time.Duration) (V, error) {
+	result, meta, err := d.remote().getAndTouch(key, ttl)
+	d.stats.lookup(err)
+	if err == nil {
+		// the item has just been given ttl, or no expiration at all
+		remoteTTL := ttl
+		if remoteTTL <= 0 {
+			remoteTTL = NoExpirationTTL
+		}
+		d.backfill(key, result, meta, remoteTTL)
+	} else if errors.Is(err, ErrNotFound) {
+		d.backfillTombstones([]string{key})
+	}
+	return result, err
+}
+
+func (d *TieredDriver[V]) Pull(key string) (V, error) {
+	defer d.invalidate(key)
+	return d.remote().Pull(key)
+}
+
+func (d *TieredDriver[V]) GetSet(key string, value V, t time.Duration) (V, error) {
+	defer d.invalidate(key)
+	return d.remote().GetSet(key, value, t)
+}
+
+func (d *TieredDriver[V]) CompareAndSwap(key string, old, value V, t time.Duration) (bool, error) {
+	defer d.invalidate(key)
+	return d.remote().CompareAndSwap(key, old, value, t)
+}
+
+func (d *TieredDriver[V]) Update(key string, t time.Duration, fn func(old V, found bool) (V, error)) (V, error) {
+	defer d.invalidate(key)
+	return d.remote().Update(key, t, fn)
+}
+
+// Scan redis is authoritative
+func (d *TieredDriver[V]) Scan(pattern string, batchSize int, fn func(key string) error) error {
+	return d.remote().Scan(pattern, batchSize, fn)
+}
+
+func (d *TieredDriver[V]) ScanItems(pattern string, batchSize int, fn func(key string, value V, ttl time.Duration) error) error {
+	return d.remote().ScanItems(pattern, batchSize, fn)
+}
+
+func (d *TieredDriver[V]) Tags(tags ...string) Driver[V] {
+	return newTaggedDriver[V](d, tags)
+}
+
+func (d *TieredDriver[V]) WithCtx(ctx context.Context) Driver[V] {
+	clone := &TieredDriver[V]{d.baseDriver}
+	clone.ctx = ctx
+	return clone
+}
+
+func (d *TieredDriver[V]) WithSerializer(serializer Serializer) Driver[V] {
+	clone := &TieredDriver[V]{d.baseDriver}
+	clone.serializer = serializer
+	return clone
+}
@@ -0,0 +1,210 @@
+package cacheit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTieredDriver[V any](t *testing.T, localTTL time.Duration, optionFns ...OptionFunc) *TieredDriver[V] {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err, "setup miniredis")
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		require.NoError(t, client.Close(), "close redis client")
+	})
+
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	driverName := nextDriverName("tiered_test")
+	err = RegisterTieredDriver(driverName, memCache, client, "cache_prefix", localTTL, optionFns...)
+	require.NoError(t, err, "register tiered driver")
+
+	driver, err := Use[V](driverName)
+	require.NoError(t, err, "use tiered driver")
+	return driver.(*TieredDriver[V])
+}
+
+func TestRegisterTieredDriverRejectsNonPositiveLocalTTL(t *testing.T) {
+	memCache := gocache.New(5*time.Minute, 10*time.Minute)
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	assert.Error(t, RegisterTieredDriver(nextDriverName("tiered_ttl"), memCache, client, "", 0))
+}
+
+func TestTieredDriver(t *testing.T) {
+	tieredDriverString := setupTieredDriver[string](t, time.Minute)
+	testCache[string](t, tieredDriverString, "test_string_key", "test_string_value")
+	testNumberCache[string](t, tieredDriverString, "test_string_key", "test_string_value")
+
+	tieredDriverStruct := setupTieredDriver[testStruct](t, time.Minute)
+	testCache[testStruct](t, tieredDriverStruct, "test_struct_key", testStructData)
+
+	tieredDriverInt := setupTieredDriver[int](t, time.Minute)
+	testNumberCache[int](t, tieredDriverInt, "test_int_key", 2)
+
+	testRememberConcurrency(t, setupTieredDriver[string](t, time.Minute))
+	testRememberStale(t, setupTieredDriver[string](t, time.Minute))
+}
+
+func TestTieredGetBackfillsLocalWithBoundedTTL(t *testing.T) {
+	driver := setupTieredDriver[string](t, 30*time.Second)
+
+	assert.NoError(t, driver.remote().Set("key", "value", time.Hour))
+	_, found := driver.memCache.Get("cache_prefix:key")
+	assert.False(t, found)
+
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", got)
+
+	local, expiration, found := driver.memCache.GetWithExpiration("cache_prefix:key")
+	assert.True(t, found)
+	assert.Equal(t, "value", local)
+	assert.LessOrEqual(t, time.Until(expiration), 30*time.Second)
+
+	// served from L1 even though L2 is gone
+	assert.NoError(t, driver.redisClient.Del(context.Background(), "cache_prefix:key").Err())
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", got)
+
+	ttl, err := driver.TTL("key")
+	assert.NoError(t, err)
+	assert.Equal(t, ItemNotExistedTTL, ttl)
+}
+
+func TestTieredBackfillBoundedByRemoteTTL(t *testing.T) {
+	driver := setupTieredDriver[string](t, time.Minute)
+
+	assert.NoError(t, driver.remote().Set("get", "value", 5*time.Second))
+	assert.NoError(t, driver.remote().Set("many", "value", 5*time.Second))
+	assert.NoError(t, driver.remote().Set("touch", "value", time.Hour))
+	assert.NoError(t, driver.remote().Forever("forever", "value"))
+
+	_, err := driver.Get("get")
+	assert.NoError(t, err)
+	_, err = driver.Many([]string{"many", "forever"})
+	assert.NoError(t, err)
+	_, err = driver.GetAndTouch("touch", 3*time.Second)
+	assert.NoError(t, err)
+
+	for key, expected := range map[string]time.Duration{"get": 5 * time.Second, "many": 5 * time.Second, "touch": 3 * time.Second, "forever": time.Minute} {
+		_, expiration, found := driver.memCache.GetWithExpiration("cache_prefix:" + key)
+		assert.True(t, found, key)
+		assert.LessOrEqual(t, time.Until(expiration), expected, key)
+		assert.Greater(t, time.Until(expiration), expected-time.Second, key)
+	}
+}
+
+func TestTieredManyBackfillsMissingKeys(t *testing.T) {
+	driver := setupTieredDriver[string](t, time.Minute)
+
+	driver.memCache.Set("cache_prefix:a", "local_a", time.Minute)
+	assert.NoError(t, driver.remote().Set("a", "remote_a", time.Minute))
+	assert.NoError(t, driver.remote().Set("b", "remote_b", time.Minute))
+
+	got, err := driver.Many([]string{"a", "b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "local_a", "b": "remote_b"}, got)
+
+	local, found := driver.memCache.Get("cache_prefix:b")
+	assert.True(t, found)
+	assert.Equal(t, "remote_b", local)
+}
+
+func TestTieredWritesInvalidateLocal(t *testing.T) {
+	driver := setupTieredDriver[int](t, time.Minute)
+
+	assert.NoError(t, driver.Set("key", 1, time.Minute))
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got)
+
+	assert.NoError(t, driver.Set("key", 2, time.Minute))
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got)
+
+	got, err = driver.Increment("key", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, got)
+	_, found := driver.memCache.Get("cache_prefix:key")
+	assert.False(t, found)
+
+	got, err = driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, got)
+
+	assert.NoError(t, driver.Forget("key"))
+	_, err = driver.Get("key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestTieredNegativeCache(t *testing.T) {
+	testNegativeCache(t, setupTieredDriver[string](t, time.Minute, WithNegativeTTL(2*time.Second)))
+}
+
+func TestTieredStoreCopies(t *testing.T) {
+	driver := setupTieredDriver[mutableItem](t, time.Minute, WithMemoryStorage(StoreCopies))
+	testIndependentValues(t, driver)
+
+	assert.NoError(t, driver.remote().Set("remote", newMutableItem(), time.Minute))
+	items, err := driver.Many([]string{"remote"})
+	assert.NoError(t, err)
+	mutate(items["remote"])
+	got, err := driver.local().Get("remote")
+	assert.NoError(t, err)
+	assert.Equal(t, newMutableItem(), got)
+}
+
+func TestTieredConcurrentWithCtx(t *testing.T) {
+	testConcurrentWithCtx(t, setupTieredDriver[string](t, time.Minute))
+}
+
+func TestTieredTouch(t *testing.T) {
+	testTouch(t, setupTieredDriver[string](t, time.Minute))
+}
+
+func TestTieredTouchCapsTombstones(t *testing.T) {
+	testTouchCapsTombstones(t, setupTieredDriver[string](t, time.Minute, WithNegativeTTL(time.Minute)))
+}
+
+func TestTieredGetAndTouchBackfillsLocal(t *testing.T) {
+	driver := setupTieredDriver[string](t, time.Minute)
+	assert.NoError(t, driver.remote().Set("key", "value", time.Minute))
+
+	got, err := driver.GetAndTouch("key", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", got)
+	local, err := driver.local().Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", local)
+	assertTTL(t, driver, "key", time.Hour)
+}
+
+func TestTieredAtomicOperations(t *testing.T) {
+	testAtomicOperations(t, setupTieredDriver[int](t, time.Minute))
+
+	driver := setupTieredDriver[string](t, time.Minute)
+	assert.NoError(t, driver.Set("key", "value", time.Minute))
+	_, err := driver.Get("key")
+	assert.NoError(t, err)
+	_, err = driver.Update("key", time.Minute, func(old string, found bool) (string, error) {
+		return old + "!", nil
+	})
+	assert.NoError(t, err)
+	_, err = driver.local().Get("key")
+	assert.ErrorIs(t, err, ErrCacheMiss, "the local copy is invalidated")
+	got, err := driver.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value!", got)
+}
@@ -9,11 +9,41 @@
 package cacheit
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/spf13/cast"
 )
 
+// randomID a random 128 bits hex id
+func randomID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// errMissingKeys an error wrapping ErrCacheMiss reporting the keys missing from the cache, nil if none
+func errMissingKeys(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrCacheMiss, strings.Join(keys, ", "))
+}
+
+// errMissingKey ErrCacheMiss if the key is missing from the cache
+func errMissingKey(missing []string) error {
+	if len(missing) > 0 {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
 func isNumeric(v any) bool {
 	switch v.(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
@@ -115,3 +145,102 @@ func toAnyE[T any](a any) (T, error) {
 	}
 	return t, nil
 }
+
+// deepCopy a deep copy of value, the exported fields of structs are copied, unexported fields and
+// channels, functions and unsafe pointers are shallow copied, cyclic values are not supported.
+func deepCopy[V any](value V) V {
+	var result V
+	reflect.ValueOf(&result).Elem().Set(deepCopyValue(reflect.ValueOf(&value).Elem()))
+	return result
+}
+
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopyValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopyValue(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(deepCopyValue(iter.Key()), deepCopyValue(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopyValue(v.Field(i)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// keyMutex mutexes by key, removed once they are released by all their holders
+type keyMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	holders int
+}
+
+// lock the mutex of key, returns the function unlocking it
+func (m *keyMutex) lock(key string) func() {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyLock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyLock{}
+		m.locks[key] = l
+	}
+	l.holders++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		if l.holders--; l.holders == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -62,3 +62,34 @@ func singleTestToAnyE[T any](t *testing.T, name string, arg any, expected T, wan
 		}
 	})
 }
+
+func TestDeepCopy(t *testing.T) {
+	type inner struct {
+		Values [2][]int
+	}
+	type value struct {
+		Ptr     *inner
+		Any     any
+		Nil     []int
+		private []int
+	}
+	original := value{
+		Ptr:     &inner{Values: [2][]int{{1}, {2}}},
+		Any:     map[string][]int{"a": {1}},
+		private: []int{1},
+	}
+	copied := deepCopy(original)
+	assert.Equal(t, original, copied)
+	assert.Nil(t, copied.Nil)
+
+	copied.Ptr.Values[0][0] = 42
+	copied.Any.(map[string][]int)["a"][0] = 42
+	assert.Equal(t, 1, original.Ptr.Values[0][0])
+	assert.Equal(t, []int{1}, original.Any.(map[string][]int)["a"])
+	// unexported fields are shallow copied
+	copied.private[0] = 42
+	assert.Equal(t, 42, original.private[0])
+
+	var nilAny any
+	assert.Nil(t, deepCopy(nilAny))
+}
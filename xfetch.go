@@ -0,0 +1,74 @@
+package cacheit
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// XFetchPolicy probabilistic early expiration of the items written by Remember, the optimal probabilistic
+// early recomputation (XFetch): a read at time now recomputes the item if
+// now - delta * Beta * log(1 - rand) >= expiry, where delta is the time the loader took to compute the item.
+type XFetchPolicy struct {
+	// Beta how early the items are recomputed, above 1 favors earlier recomputations, 1 by default
+	Beta float64
+	// Rand random source returning numbers in [0, 1), it must be safe for concurrent use, math/rand.Float64 by default
+	Rand func() float64
+}
+
+// expired report whether an item written with meta is recomputed early
+func (p *XFetchPolicy) expired(meta entryMeta) bool {
+	if meta.ttl <= 0 || meta.delta <= 0 || meta.writtenAt.IsZero() {
+		return false
+	}
+	beta, random := p.Beta, p.Rand
+	if beta <= 0 {
+		beta = 1
+	}
+	if random == nil {
+		random = rand.Float64
+	}
+	gap := time.Duration(float64(meta.delta) * beta * -math.Log(1-random()))
+	return !time.Now().Add(gap).Before(meta.writtenAt.Add(meta.ttl))
+}
+
+// rememberEarly implements Remember with probabilistic early expiration on top of an entryStore, the values
+// are stored with the time their loader took. A failed early recomputation returns the cached value.
+func rememberEarly[V any](store entryStore[V], key string, ttl time.Duration, callback func() (V, error), force bool) (V, error) {
+	d := store.base()
+	refresh := func() (V, error) {
+		start := time.Now()
+		result, err := load(d.stats, callback)
+		if errors.Is(err, ErrNotFound) {
+			if err := store.setTombstones([]string{key}); err != nil {
+				return result, err
+			}
+		}
+		if err != nil {
+			return result, err
+		}
+		meta := entryMeta{writtenAt: time.Now(), delta: time.Since(start), ttl: ttl}
+		return result, store.setEntry(key, result, meta, ttl)
+	}
+	cacheKey, err := d.getCacheKey(key)
+	if err != nil {
+		var result V
+		return result, d.stats.fail(err)
+	}
+	if force {
+		return doFlight(d.ctx, d.group, cacheKey, refresh)
+	}
+	result, meta, err := store.getEntry(key)
+	d.stats.lookup(err)
+	if errors.Is(err, ErrNotFound) || err == nil && !d.xfetch.expired(meta) {
+		return result, err
+	}
+	if err != nil {
+		return doFlight(d.ctx, d.group, cacheKey, refresh)
+	}
+	if refreshed, err := doFlight(d.ctx, d.group, cacheKey, refresh); err == nil {
+		return refreshed, nil
+	}
+	return result, nil
+}
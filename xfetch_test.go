@@ -0,0 +1,106 @@
+package cacheit
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXFetchPolicyExpired(t *testing.T) {
+	highest := func() float64 { return 1 - 1e-12 } // -log(1e-12) is about 27.6
+	meta := entryMeta{writtenAt: time.Now(), delta: time.Second, ttl: time.Minute}
+
+	assert.False(t, (&XFetchPolicy{Rand: func() float64 { return 0 }}).expired(meta))
+	assert.False(t, (&XFetchPolicy{Rand: highest}).expired(meta), "27.6s ahead of a ttl of one minute")
+	assert.True(t, (&XFetchPolicy{Beta: 3, Rand: highest}).expired(meta), "82.8s ahead of a ttl of one minute")
+
+	expired := meta
+	expired.writtenAt = time.Now().Add(-2 * time.Minute)
+	assert.True(t, (&XFetchPolicy{Rand: func() float64 { return 0 }}).expired(expired))
+
+	assert.False(t, (&XFetchPolicy{Beta: 3, Rand: highest}).expired(entryMeta{writtenAt: time.Now(), delta: time.Second}), "no ttl")
+	assert.False(t, (&XFetchPolicy{Beta: 3, Rand: highest}).expired(entryMeta{writtenAt: time.Now(), ttl: time.Minute}), "no delta")
+}
+
+// setupXFetch an xfetch option recomputing every item while early is set
+func setupXFetch() (OptionFunc, *int32) {
+	var early int32
+	return WithXFetch(XFetchPolicy{Beta: 1e6, Rand: func() float64 {
+		if atomic.LoadInt32(&early) == 1 {
+			return 0.99
+		}
+		return 0
+	}}), &early
+}
+
+func testXFetch(t *testing.T, driver Driver[string], early *int32) {
+	var calls int32
+	loader := func() (string, error) {
+		time.Sleep(time.Millisecond)
+		return fmt.Sprintf("v%d", atomic.AddInt32(&calls, 1)), nil
+	}
+	remember := func(callback func() (string, error)) string {
+		t.Helper()
+		got, err := driver.Remember("key", time.Minute, callback, false)
+		assert.NoError(t, err)
+		return got
+	}
+	assert.Equal(t, "v1", remember(loader))
+	assert.Equal(t, "v1", remember(loader))
+
+	atomic.StoreInt32(early, 1)
+	assert.Equal(t, "v2", remember(loader), "recomputed early")
+	assert.Equal(t, "v2", remember(func() (string, error) {
+		return "", errors.New("source down")
+	}), "a failed early recomputation returns the cached value")
+
+	_, err := driver.RememberForever("forever", loader, false)
+	assert.NoError(t, err)
+	got, err := driver.RememberForever("forever", loader, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "v3", got, "items without expiration are never recomputed early")
+	atomic.StoreInt32(early, 0)
+}
+
+func TestRedisXFetch(t *testing.T) {
+	option, early := setupXFetch()
+	testXFetch(t, setupRedisDriverWithPrefix[string](t, "cache_prefix", option), early)
+}
+
+func TestGoCacheXFetch(t *testing.T) {
+	option, early := setupXFetch()
+	testXFetch(t, setupGoCacheDriverWithPrefix[string](t, "cache_prefix", option), early)
+}
+
+func TestTieredXFetch(t *testing.T) {
+	option, early := setupXFetch()
+	testXFetch(t, setupTieredDriver[string](t, time.Minute, option), early)
+}
+
+func TestXFetchRemember(t *testing.T) {
+	option, _ := setupXFetch()
+	testRememberConcurrency(t, setupRedisDriverWithPrefix[string](t, "cache_prefix", option))
+	testNegativeCache(t, setupGoCacheDriverWithPrefix[string](t, "cache_prefix", option, WithNegativeTTL(2*time.Second)))
+}
+
+func TestXFetchRecordsJitteredTTL(t *testing.T) {
+	option, _ := setupXFetch()
+	jitter := WithTTLJitter(JitterPolicy{Max: time.Minute, Rand: func() float64 { return 0.5 }})
+	for name, store := range map[string]entryStore[string]{
+		"redis":    setupRedisDriverWithPrefix[string](t, "cache_prefix", option, jitter),
+		"go-cache": setupGoCacheDriverWithPrefix[string](t, "cache_prefix", option, jitter),
+		"tiered":   setupTieredDriver[string](t, time.Minute, option, jitter),
+	} {
+		_, err := store.(Driver[string]).Remember("key", time.Minute, func() (string, error) {
+			return "value", nil
+		}, false)
+		assert.NoError(t, err, name)
+		_, meta, err := store.getEntry("key")
+		assert.NoError(t, err, name)
+		assert.Equal(t, 90*time.Second, meta.ttl, name)
+	}
+}